@@ -0,0 +1,173 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/sujunbo/micro/log"
+	"github.com/sujunbo/micro/transport/incoming"
+)
+
+// AuditRecord 一条数据变更(INSERT/UPDATE/DELETE)的审计记录
+type AuditRecord struct {
+	Time      time.Time `json:"time" db:"-"`
+	DB        string    `json:"db" db:"db_name"`
+	Table     string    `json:"table" db:"table_name"`
+	Operation string    `json:"operation" db:"operation"`
+	// 参数已替换为 ?
+	Statement string `json:"statement" db:"statement"`
+	// -1 表示未知(未经 Session 执行)
+	Rows     int64  `json:"rows" db:"rows_affected"`
+	User     string `json:"user" db:"user"`
+	ClientIP string `json:"client_ip" db:"client_ip"`
+	Method   string `json:"method" db:"method"`
+	Err      string `json:"err,omitempty" db:"err"`
+	Ctime    int64  `json:"-" db:"ctime"`
+}
+
+type AuditWriter interface {
+	Write(r *AuditRecord) error
+}
+
+// Audit 审计配置, 通过 Connection.EnableAudit 开启
+type Audit struct {
+	Writer AuditWriter
+	// 读取调用方身份的入站 header, 默认 x-user-id; 只读 header, 不读 url 参数, 避免伪造
+	UserKey string
+	// 自定义身份提取, 为空时读取入站 header 中的 UserKey 与入站元数据(gin 写入)中的 client-ip
+	Identity func(ctx context.Context) (user string, clientIP string)
+	// 不审计的表, 如审计表自身
+	SkipTables []string
+
+	skip map[string]bool
+}
+
+func (c *Connection) EnableAudit(a *Audit) {
+	if a.UserKey == "" {
+		a.UserKey = "x-user-id"
+	}
+	a.skip = map[string]bool{}
+	for _, t := range a.SkipTables {
+		a.skip[t] = true
+	}
+
+	if r, ok := c.EventReceiver.(*sqlEventReceiver); ok {
+		r.audit = a
+	}
+}
+
+type auditKey struct{}
+
+type auditEntry struct {
+	audit  *Audit
+	record AuditRecord
+	once   sync.Once
+}
+
+func (a *Audit) begin(ctx context.Context, dbname, driver, op, table, query string) *auditEntry {
+	if a.skip[table] {
+		return nil
+	}
+
+	e := &auditEntry{audit: a, record: AuditRecord{
+		Time:      time.Now(),
+		DB:        dbname,
+		Table:     table,
+		Operation: op,
+		Statement: normalizeQuery(driver, query),
+		Rows:      -1,
+	}}
+
+	if a.Identity != nil {
+		e.record.User, e.record.ClientIP = a.Identity(ctx)
+	} else {
+		md := incoming.Metadata(ctx)
+		e.record.User = incoming.Header(ctx).Get(a.UserKey)
+		e.record.ClientIP = firstValue(md["client-ip"])
+		e.record.Method = firstValue(md["method-name"])
+	}
+	return e
+}
+
+func firstValue(vs []string) string {
+	if len(vs) == 0 {
+		return ""
+	}
+	return vs[0]
+}
+
+func (e *auditEntry) fail(err error) {
+	e.record.Err = err.Error()
+}
+
+func (e *auditEntry) finish(rows int64) {
+	e.once.Do(func() {
+		e.record.Rows = rows
+		e.record.Ctime = e.record.Time.Unix()
+		if err := e.audit.Writer.Write(&e.record); err != nil {
+			log.Errorf("DB audit write db:%v table:%v err:%v", e.record.DB, e.record.Table, err)
+		}
+	})
+}
+
+func auditFromContext(ctx context.Context) *auditEntry {
+	e, _ := ctx.Value(auditKey{}).(*auditEntry)
+	return e
+}
+
+type auditFileWriter struct {
+	mutex sync.Mutex
+	file  *log.RotateFile
+}
+
+// NewAuditFileWriter 以 json 行写入独立的滚动日志文件
+func NewAuditFileWriter(path string, opts ...log.RotateOption) AuditWriter {
+	return &auditFileWriter{file: log.NewRotateFile(path, opts...)}
+}
+
+func (w *auditFileWriter) Write(r *AuditRecord) error {
+	buf, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+	_, err = w.file.Write(append(buf, '\n'))
+	return err
+}
+
+type auditTableWriter struct {
+	conn  *Connection
+	table string
+}
+
+// NewAuditTableWriter 写入审计表, 若 conn 开启了审计, 需将 table 加入 SkipTables:
+//
+//	CREATE TABLE `sql_audit_tab` (
+//	  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+//	  `db_name` varchar(64) NOT NULL,
+//	  `table_name` varchar(128) NOT NULL,
+//	  `operation` varchar(16) NOT NULL,
+//	  `statement` text NOT NULL,
+//	  `rows_affected` bigint NOT NULL,
+//	  `user` varchar(128) NOT NULL,
+//	  `client_ip` varchar(64) NOT NULL,
+//	  `method` varchar(128) NOT NULL,
+//	  `err` varchar(1024) NOT NULL,
+//	  `ctime` bigint NOT NULL,
+//	  PRIMARY KEY (`id`)
+//	) ENGINE=InnoDB;
+func NewAuditTableWriter(conn *Connection, table string) AuditWriter {
+	return &auditTableWriter{conn: conn, table: table}
+}
+
+func (w *auditTableWriter) Write(r *AuditRecord) (err error) {
+	_, err = w.conn.NewSession().InsertInto(w.table).
+		Columns("db_name", "table_name", "operation", "statement", "rows_affected", "user", "client_ip", "method", "err", "ctime").
+		Record(r).
+		Exec()
+	return
+}
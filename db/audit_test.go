@@ -0,0 +1,67 @@
+package db_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/db/dbtest"
+	"github.com/sujunbo/micro/transport/http/gin"
+)
+
+type auditRecords []*db.AuditRecord
+
+func (r *auditRecords) Write(record *db.AuditRecord) error {
+	*r = append(*r, record)
+	return nil
+}
+
+func TestAuditIdentityFromHeader(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+	var records auditRecords
+	conn.EnableAudit(&db.Audit{Writer: &records})
+
+	if _, err := conn.Exec("CREATE TABLE user_tab (id INTEGER, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// url 参数与 header 同名, 审计只认 header
+	md := gin.Join(gin.Metadata{}, gin.Metadata{"x-user-id": {"admin"}})
+	md.Set("client-ip", "10.0.0.1")
+	ctx := gin.NewContextFromMetadata(context.Background(), md)
+	ctx = gin.NewContextFromHeader(ctx, http.Header{"X-User-Id": {"alice"}})
+
+	sess := conn.NewSessionContext(ctx)
+	if _, err := sess.Exec(sess.InsertInto("user_tab").Pair("id", 1).Pair("name", "bob")); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 {
+		t.Fatalf("expect 1 audit record, got %d", len(records))
+	}
+	r := records[0]
+	if r.User != "alice" || r.ClientIP != "10.0.0.1" || r.Table != "user_tab" || r.Operation != db.OpInsert || r.Rows != 1 {
+		t.Fatalf("unexpected audit record %+v", r)
+	}
+}
+
+func TestAuditWithoutHeader(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+	var records auditRecords
+	conn.EnableAudit(&db.Audit{Writer: &records})
+
+	if _, err := conn.Exec("CREATE TABLE user_tab (id INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := gin.NewContextFromMetadata(context.Background(), gin.Metadata{"x-user-id": {"admin"}})
+	sess := conn.NewSessionContext(ctx)
+	if _, err := sess.Exec(sess.InsertInto("user_tab").Pair("id", 1)); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(records) != 1 || records[0].User != "" {
+		t.Fatalf("expect empty user without inbound header, got %+v", records)
+	}
+}
@@ -0,0 +1,72 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/sujunbo/micro/util/breaker"
+)
+
+var (
+	// ErrCircuitOpen 熔断期间直接失败, 不占用连接池
+	ErrCircuitOpen = breaker.ErrOpen
+	// ErrTooManyQueries 并发查询数超过 Options.MaxInFlight
+	ErrTooManyQueries = errors.New("db too many in-flight queries")
+)
+
+// guard 对 Session 上的查询做熔断与并发限制
+type guard struct {
+	breaker  *breaker.Breaker
+	inflight chan struct{}
+}
+
+func newGuard(option *Options) *guard {
+	if option.Breaker == nil && option.MaxInFlight <= 0 {
+		return nil
+	}
+
+	g := &guard{breaker: option.Breaker}
+	if option.MaxInFlight > 0 {
+		g.inflight = make(chan struct{}, option.MaxInFlight)
+	}
+	return g
+}
+
+func (g *guard) do(fn func() error) (err error) {
+	if g == nil {
+		return fn()
+	}
+
+	if g.inflight != nil {
+		select {
+		case g.inflight <- struct{}{}:
+			defer func() { <-g.inflight }()
+		default:
+			return ErrTooManyQueries
+		}
+	}
+
+	if g.breaker == nil {
+		return fn()
+	}
+
+	done, err := g.breaker.Allow()
+	if err != nil {
+		return
+	}
+
+	start := time.Now()
+	err = fn()
+	done(isFailure(err), time.Since(start))
+	return
+}
+
+// isFailure 未找到记录与调用方取消不计入熔断, Session 返回的取消错误已由 ctxError 包装为 ErrQueryCanceled
+func isFailure(err error) bool {
+	return err != nil &&
+		!errors.Is(err, dbr.ErrNotFound) &&
+		!errors.Is(err, ErrQueryCanceled) &&
+		!errors.Is(err, context.Canceled)
+}
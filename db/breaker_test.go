@@ -0,0 +1,65 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/db/dbtest"
+	"github.com/sujunbo/micro/util/breaker"
+)
+
+func TestBreakerIgnoresCanceledQuery(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+
+	cb := breaker.New(breaker.WithName(t.Name()), breaker.WithMinRequests(1))
+	guarded, err := db.Wrap(conn.DB, &db.Options{
+		Name:    t.Name(),
+		Driver:  db.DriverSQLite3,
+		Breaker: cb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { guarded.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	sess := guarded.NewSessionContext(ctx)
+	var n int
+	_, err = sess.Load(sess.Select("1"), &n)
+	if !errors.Is(err, db.ErrQueryCanceled) {
+		t.Fatalf("expect ErrQueryCanceled, got %v", err)
+	}
+
+	if state := cb.State(); state != breaker.StateClosed {
+		t.Fatalf("expect breaker closed after canceled query, got %v", state)
+	}
+}
+
+func TestBreakerOpensOnQueryError(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+
+	cb := breaker.New(breaker.WithName(t.Name()), breaker.WithMinRequests(1))
+	guarded, err := db.Wrap(conn.DB, &db.Options{
+		Name:    t.Name(),
+		Driver:  db.DriverSQLite3,
+		Breaker: cb,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { guarded.Close() })
+
+	sess := guarded.NewSessionContext(context.Background())
+	var n int
+	if _, err = sess.Load(sess.Select("*").From("missing"), &n); err == nil {
+		t.Fatal("expect error for missing table")
+	}
+
+	if state := cb.State(); state != breaker.StateOpen {
+		t.Fatalf("expect breaker open after failed query, got %v", state)
+	}
+}
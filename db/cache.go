@@ -0,0 +1,299 @@
+package db
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/sync/singleflight"
+)
+
+var (
+	cacheEvents = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "db",
+		Name:      "cache_events_total",
+		Help:      "Query cache hits, misses and evictions by database.",
+	}, []string{"db", "event"})
+)
+
+func init() {
+	prometheus.MustRegister(cacheEvents)
+}
+
+type CacheStats struct {
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+	Size      int
+}
+
+type cacheEntry struct {
+	key    string
+	table  string
+	value  reflect.Value
+	count  int
+	expire time.Time
+}
+
+// Cache 查询结果的进程内 LRU 缓存, 条目按 ttl 过期, 并发的相同查询只访问一次数据库;
+// 经同一 Connection 执行的写操作会按表名失效对应条目, Session.Begin 开启的事务在提交后再次失效.
+// 缓存的结果在调用方之间共享, 不能修改
+type Cache struct {
+	name  string
+	size  int
+	ttl   time.Duration
+	group singleflight.Group
+
+	mutex   sync.Mutex
+	ll      *list.List
+	items   map[string]*list.Element
+	byTable map[string]map[string]struct{}
+	// 每次失效递增, 防止失效期间加载的旧结果被写回
+	gens map[string]uint64
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+}
+
+func NewCache(size int, ttl time.Duration) *Cache {
+	return &Cache{
+		size:    size,
+		ttl:     ttl,
+		ll:      list.New(),
+		items:   map[string]*list.Element{},
+		byTable: map[string]map[string]struct{}{},
+		gens:    map[string]uint64{},
+	}
+}
+
+// EnableCache 为连接开启查询缓存, 通过 Session.LoadCached 使用
+func (c *Connection) EnableCache(cache *Cache) {
+	cache.name = c.name
+	c.cache = cache
+	if r, ok := c.EventReceiver.(*sqlEventReceiver); ok {
+		r.cache = cache
+	}
+}
+
+func (c *Connection) Cache() *Cache {
+	return c.cache
+}
+
+// LoadCached 与 Load 相同, 连接未开启缓存时直接查询
+func (s *Session) LoadCached(stmt *dbr.SelectStmt, value interface{}) (int, error) {
+	if s.cache == nil {
+		return s.Load(stmt, value)
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return 0, dbr.ErrInvalidPointer
+	}
+
+	buf := dbr.NewBuffer()
+	if err := stmt.Build(s.Dialect, buf); err != nil {
+		return 0, err
+	}
+	query := buf.String()
+	key := fmt.Sprintf("%v|%#v|%v", query, buf.Value(), v.Elem().Type())
+
+	if e, ok := s.cache.get(key); ok {
+		v.Elem().Set(e.value)
+		return e.count, nil
+	}
+
+	// 共享的加载不受首个调用方 ctx 取消的影响, 各调用方仍按自己的 ctx 返回
+	ch := s.cache.group.DoChan(key, func() (interface{}, error) {
+		ctx, cancel := detachContext(s.ctx, s.Timeout)
+		defer cancel()
+
+		tableName := table(query)
+		gen := s.cache.gen(tableName)
+		nv := reflect.New(v.Elem().Type())
+		loader := &Session{Session: s.Session, ctx: ctx, guard: s.guard}
+		n, err := loader.Load(stmt, nv.Interface())
+		if err != nil {
+			return nil, err
+		}
+
+		e := &cacheEntry{key: key, table: tableName, value: nv.Elem(), count: n}
+		s.cache.add(e, gen)
+		return e, nil
+	})
+
+	var res singleflight.Result
+	select {
+	case res = <-ch:
+	case <-s.ctx.Done():
+		return 0, ctxError(s.ctx.Err())
+	}
+	if res.Err != nil {
+		return 0, res.Err
+	}
+
+	e := res.Val.(*cacheEntry)
+	v.Elem().Set(e.value)
+	return e.count, nil
+}
+
+func (c *Cache) get(key string) (e *cacheEntry, ok bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	el, ok := c.items[key]
+	if ok {
+		e = el.Value.(*cacheEntry)
+		if time.Now().After(e.expire) {
+			c.remove(el)
+			ok = false
+		}
+	}
+
+	if !ok {
+		atomic.AddUint64(&c.misses, 1)
+		cacheEvents.WithLabelValues(c.name, "miss").Inc()
+		return nil, false
+	}
+
+	c.ll.MoveToFront(el)
+	atomic.AddUint64(&c.hits, 1)
+	cacheEvents.WithLabelValues(c.name, "hit").Inc()
+	return e, true
+}
+
+func (c *Cache) gen(table string) uint64 {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.gens[table]
+}
+
+func (c *Cache) add(e *cacheEntry, gen uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if c.gens[e.table] != gen {
+		return
+	}
+
+	e.expire = time.Now().Add(c.ttl)
+	if el, ok := c.items[e.key]; ok {
+		c.remove(el)
+	}
+
+	c.items[e.key] = c.ll.PushFront(e)
+	if c.byTable[e.table] == nil {
+		c.byTable[e.table] = map[string]struct{}{}
+	}
+	c.byTable[e.table][e.key] = struct{}{}
+
+	for c.size > 0 && c.ll.Len() > c.size {
+		c.remove(c.ll.Back())
+		atomic.AddUint64(&c.evictions, 1)
+		cacheEvents.WithLabelValues(c.name, "eviction").Inc()
+	}
+}
+
+func (c *Cache) remove(el *list.Element) {
+	e := c.ll.Remove(el).(*cacheEntry)
+	delete(c.items, e.key)
+	if keys := c.byTable[e.table]; keys != nil {
+		delete(keys, e.key)
+		if len(keys) == 0 {
+			delete(c.byTable, e.table)
+		}
+	}
+}
+
+// detachedContext 保留 parent 的 value(trace 等), 但不继承其取消与超时
+type detachedContext struct {
+	context.Context
+}
+
+func (detachedContext) Deadline() (deadline time.Time, ok bool) {
+	return
+}
+
+func (detachedContext) Done() <-chan struct{} {
+	return nil
+}
+
+func (detachedContext) Err() error {
+	return nil
+}
+
+func detachContext(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	ctx := context.Context(detachedContext{parent})
+	if timeout > 0 {
+		return context.WithTimeout(ctx, timeout)
+	}
+	return context.WithCancel(ctx)
+}
+
+// txEventReceiver 记录事务内写过的表, 提交后再次失效, 避免提交前读到的旧数据被缓存
+type txEventReceiver struct {
+	*sqlEventReceiver
+	cache *Cache
+
+	mutex  sync.Mutex
+	tables map[string]struct{}
+}
+
+func (r *txEventReceiver) TimingKv(eventName string, nanoseconds int64, kvs map[string]string) {
+	if eventName == "dbr.exec" {
+		r.mutex.Lock()
+		r.tables[table(kvs["sql"])] = struct{}{}
+		r.mutex.Unlock()
+	}
+	r.sqlEventReceiver.TimingKv(eventName, nanoseconds, kvs)
+}
+
+func (r *txEventReceiver) Event(eventName string) {
+	if eventName == "dbr.commit" {
+		r.mutex.Lock()
+		for t := range r.tables {
+			r.cache.Invalidate(t)
+		}
+		r.mutex.Unlock()
+	}
+	r.sqlEventReceiver.Event(eventName)
+}
+
+// Invalidate 删除表 table 的全部缓存条目
+func (c *Cache) Invalidate(table string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.gens[table]++
+	for key := range c.byTable[table] {
+		c.remove(c.items[key])
+	}
+}
+
+func (c *Cache) Purge() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.ll.Init()
+	c.items = map[string]*list.Element{}
+	c.byTable = map[string]map[string]struct{}{}
+}
+
+func (c *Cache) Stats() CacheStats {
+	c.mutex.Lock()
+	size := c.ll.Len()
+	c.mutex.Unlock()
+
+	return CacheStats{
+		Hits:      atomic.LoadUint64(&c.hits),
+		Misses:    atomic.LoadUint64(&c.misses),
+		Evictions: atomic.LoadUint64(&c.evictions),
+		Size:      size,
+	}
+}
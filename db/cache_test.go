@@ -0,0 +1,63 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sujunbo/micro/db"
+)
+
+func TestCacheInvalidateOnCommit(t *testing.T) {
+	conn, err := db.New(&db.Options{
+		Name:         t.Name(),
+		Driver:       db.DriverSQLite3,
+		DataSource:   filepath.Join(t.TempDir(), "cache.db"),
+		MaxOpenConns: 2,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	conn.EnableCache(db.NewCache(16, time.Minute))
+
+	if _, err = conn.Exec("CREATE TABLE shop_tab (id INTEGER, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Exec("INSERT INTO shop_tab VALUES (1, 'old')"); err != nil {
+		t.Fatal(err)
+	}
+
+	sess := conn.NewSessionContext(context.Background())
+	load := func() string {
+		var name string
+		if _, err := sess.LoadCached(sess.Select("name").From("shop_tab").Where("id = ?", 1), &name); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+
+	tx, err := sess.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	if _, err = tx.Update("shop_tab").Set("name", "new").Where("id = ?", 1).Exec(); err != nil {
+		t.Fatal(err)
+	}
+
+	// 提交前在事务外读到旧值并写入缓存
+	if name := load(); name != "old" {
+		t.Fatalf("expect old before commit, got %v", name)
+	}
+
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if name := load(); name != "new" {
+		t.Fatalf("expect new after commit, got %v", name)
+	}
+}
@@ -1,23 +1,54 @@
 package db
 
 import (
-	"fmt"
+	"database/sql"
 	_ "github.com/go-sql-driver/mysql"
 	"github.com/gocraft/dbr"
+	"github.com/prometheus/client_golang/prometheus"
 	"time"
 )
 
 type Connection struct {
 	*dbr.Connection
+	name         string
+	queryTimeout time.Duration
+	collector    prometheus.Collector
+	cache        *Cache
+	guard        *guard
+}
+
+func (c *Connection) Name() string {
+	return c.name
+}
+
+func (c *Connection) Close() error {
+	unregister(c)
+	return c.Connection.Close()
 }
 
 func (c *Connection) NewSession() *dbr.Session {
-	return c.Connection.NewSession(nil)
+	sess := c.Connection.NewSession(c.EventReceiver)
+	sess.Timeout = c.queryTimeout
+	return sess
 }
 
+// Open 与 New 相同, 出错时 panic
 func Open(option *Options) *Connection {
+	c, err := New(option)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+// New 打开连接, postgres/sqlite3 驱动需由调用方 import 注册
+func New(option *Options) (c *Connection, err error) {
+	if option.Driver == "" {
+		option.Driver = DriverMySQL
+	}
+
 	if option.Port == 0 {
-		option.Port = 3306
+		option.Port = option.defaultPort()
 	}
 
 	if option.Host == "" {
@@ -25,22 +56,53 @@ func Open(option *Options) *Connection {
 	}
 
 	if option.DataSource == "" {
-		option.DataSource = fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=true&loc=Local",
-			option.UserName,
-			option.Password,
-			fmt.Sprintf("%s:%d", option.Host, option.Port),
-			option.DBName,
-		)
+		if option.DataSource, err = option.dsn(); err != nil {
+			return
+		}
 	}
+
+	sqlDB, err := sql.Open(option.Driver, option.DataSource)
+	if err != nil {
+		return
+	}
+
+	if c, err = Wrap(sqlDB, option); err != nil {
+		sqlDB.Close()
+	}
+	return
+}
+
+// Wrap 基于已打开的 sql.DB 创建连接(如 sqlmock), option.Driver 决定方言
+func Wrap(sqlDB *sql.DB, option *Options) (c *Connection, err error) {
 	if option.Driver == "" {
-		option.Driver = "mysql"
+		option.Driver = DriverMySQL
 	}
-	conn, err := dbr.Open(option.Driver, option.DataSource, NewEventReceiver(dbName(option.DataSource), int64(time.Millisecond)*200))
+
+	d, err := newDialect(option.Driver)
 	if err != nil {
-		panic(err)
+		return
 	}
-	conn.Dialect = &mysql{}
+
+	name, err := option.name()
+	if err != nil {
+		return
+	}
+
+	receiver := NewEventReceiver(name, int64(time.Millisecond)*200)
+	receiver.system = option.Driver
+	receiver.tracerProvider = option.TracerProvider
+	conn := &dbr.Connection{DB: sqlDB, EventReceiver: receiver, Dialect: d}
 	conn.SetMaxIdleConns(option.MaxIdleConns)
 	conn.SetMaxOpenConns(option.MaxOpenConns)
-	return &Connection{Connection: conn}
+	conn.SetConnMaxLifetime(option.ConnMaxLifetime)
+	conn.SetConnMaxIdleTime(option.ConnMaxIdleTime)
+
+	c = &Connection{
+		Connection:   conn,
+		name:         name,
+		queryTimeout: option.QueryTimeout,
+		guard:        newGuard(option),
+	}
+	register(c)
+	return
 }
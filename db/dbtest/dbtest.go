@@ -0,0 +1,71 @@
+// Package dbtest 为使用 db.Connection 的代码提供测试用连接, 无需真实 MySQL.
+// 未调用 log.InitLogger 时 db 的日志输出到标准库 log, 可用 log.SetLogger(log.NopLogger) 关闭
+package dbtest
+
+import (
+	"fmt"
+	"sync/atomic"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sujunbo/micro/db"
+)
+
+var seq int64
+
+// NewSQLite 返回 SQLite 内存库连接, 测试结束时关闭; 每次调用为独立的库.
+// 内存库在最后一个连接关闭时销毁, 因此固定使用单个连接
+func NewSQLite(tb testing.TB) *db.Connection {
+	tb.Helper()
+
+	name := fmt.Sprintf("dbtest_%d", atomic.AddInt64(&seq, 1))
+	conn, err := db.New(&db.Options{
+		Name:       name,
+		Driver:     db.DriverSQLite3,
+		DataSource: fmt.Sprintf("file:%s?mode=memory&cache=shared", name),
+
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		tb.Fatalf("dbtest open sqlite err:%v", err)
+	}
+
+	tb.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// NewMock 返回基于 sqlmock 的连接, driver 决定方言(默认 mysql), matcher 为空时按正则匹配;
+// dbr 会将参数插值进 sql, 期望中的 sql 应包含实际值
+func NewMock(tb testing.TB, driver string, matcher sqlmock.QueryMatcher) (*db.Connection, sqlmock.Sqlmock) {
+	tb.Helper()
+
+	if matcher == nil {
+		matcher = sqlmock.QueryMatcherRegexp
+	}
+	sqlDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(matcher))
+	if err != nil {
+		tb.Fatalf("dbtest sqlmock err:%v", err)
+	}
+
+	// sqlmock 只有一个连接, 关闭后无法重新打开, 因此保留为空闲连接
+	conn, err := db.Wrap(sqlDB, &db.Options{
+		Name:   fmt.Sprintf("dbtest_mock_%d", atomic.AddInt64(&seq, 1)),
+		Driver: driver,
+
+		MaxIdleConns: 1,
+		MaxOpenConns: 1,
+	})
+	if err != nil {
+		tb.Fatalf("dbtest wrap sqlmock err:%v", err)
+	}
+
+	tb.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			tb.Errorf("dbtest sqlmock expectations err:%v", err)
+		}
+		conn.Close()
+	})
+	return conn, mock
+}
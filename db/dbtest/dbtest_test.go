@@ -0,0 +1,173 @@
+package dbtest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sujunbo/micro/db"
+)
+
+// recordTB 记录断言失败而不使测试失败, 用于测试断言本身
+type recordTB struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recordTB) Helper() {}
+
+func (r *recordTB) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, format)
+}
+
+type shop struct {
+	ID       int64  `db:"id"`
+	ShopID   int64  `db:"shop_id"`
+	Name     string `db:"name"`
+	BuyerNum int64  `db:"buyer_num"`
+}
+
+const shopSchema = "CREATE TABLE crm_shop_attach_tab (id INTEGER PRIMARY KEY, shop_id INTEGER, name TEXT, buyer_num INTEGER NOT NULL DEFAULT 0)"
+
+func loadShops(t *testing.T, conn *db.Connection) (shops []shop) {
+	t.Helper()
+	if _, err := conn.NewSession().Select("*").From("crm_shop_attach_tab").OrderAsc("id").Load(&shops); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestNewSQLiteQueryError(t *testing.T) {
+	conn := NewSQLite(t)
+
+	var n int
+	_, err := conn.NewSession().Select("*").From("missing").Load(&n)
+	if err == nil {
+		t.Fatal("expect error for missing table")
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	files := map[string]string{
+		"shops.yaml": `
+crm_shop_attach_tab:
+  - {id: 1, shop_id: 439510, name: "a", buyer_num: 9}
+  - {id: 2, shop_id: 439511, name: "b"}
+`,
+		"shops.json": `{"crm_shop_attach_tab": [
+  {"id": 1, "shop_id": 439510, "name": "a", "buyer_num": 9},
+  {"id": 2, "shop_id": 439511, "name": "b"}
+]}`,
+	}
+	want := []shop{{1, 439510, "a", 9}, {2, 439511, "b", 0}}
+
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			conn := NewSQLite(t)
+			Exec(t, conn, shopSchema)
+			LoadFixtures(t, conn, path)
+
+			got := loadShops(t, conn)
+			if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+				t.Fatalf("expect %+v, got %+v", want, got)
+			}
+		})
+	}
+}
+
+func TestInsertFixtures(t *testing.T) {
+	conn := NewSQLite(t)
+	Exec(t, conn, shopSchema, "CREATE TABLE user_tab (id INTEGER PRIMARY KEY, name TEXT)")
+
+	InsertFixtures(t, conn, Fixtures{
+		"crm_shop_attach_tab": {{"id": 1, "shop_id": 439510, "name": "a"}},
+		"user_tab":            {{"id": 1, "name": "alice"}, {"id": 2, "name": "bob"}},
+	})
+
+	if got := loadShops(t, conn); len(got) != 1 || got[0] != (shop{1, 439510, "a", 0}) {
+		t.Fatalf("unexpected shops %+v", got)
+	}
+
+	var names []string
+	if _, err := conn.NewSession().Select("name").From("user_tab").OrderAsc("id").Load(&names); err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 2 || names[0] != "alice" || names[1] != "bob" {
+		t.Fatalf("unexpected users %v", names)
+	}
+}
+
+func TestRecorder(t *testing.T) {
+	conn := NewSQLite(t)
+	Exec(t, conn, shopSchema)
+	r := NewRecorder(conn)
+
+	sess := conn.NewSession()
+	if _, err := sess.InsertInto("crm_shop_attach_tab").Pair("id", 1).Pair("shop_id", 439510).Pair("name", "a").Exec(); err != nil {
+		t.Fatal(err)
+	}
+	loadShops(t, conn)
+
+	r.AssertExecuted(t, `^INSERT INTO "crm_shop_attach_tab"`)
+	r.AssertExecuted(t, `^SELECT \* FROM crm_shop_attach_tab`)
+	r.AssertNotExecuted(t, `^DELETE`)
+	r.AssertCount(t, 2)
+
+	// 断言失败时报告错误
+	tb := &recordTB{TB: t}
+	r.AssertExecuted(tb, `^DELETE`)
+	r.AssertNotExecuted(tb, `^INSERT`)
+	r.AssertCount(tb, 3)
+	if len(tb.errors) != 3 {
+		t.Fatalf("expect 3 assertion failures, got %v", tb.errors)
+	}
+
+	r.Reset()
+	r.AssertCount(t, 0)
+}
+
+func TestNewMock(t *testing.T) {
+	t.Run("mysql", func(t *testing.T) {
+		conn, mock := NewMock(t, db.DriverMySQL, nil)
+		mock.ExpectQuery(`SELECT name FROM crm_shop_attach_tab WHERE \(id = 1\)`).
+			WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("a"))
+		mock.ExpectExec("UPDATE `crm_shop_attach_tab` SET `buyer_num` = 10 WHERE \\(id = 1\\)").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		sess := conn.NewSession()
+		var name string
+		if err := sess.Select("name").From("crm_shop_attach_tab").Where("id = ?", 1).LoadOne(&name); err != nil || name != "a" {
+			t.Fatalf("expect a, got %q %v", name, err)
+		}
+		if _, err := sess.Update("crm_shop_attach_tab").Set("buyer_num", 10).Where("id = ?", 1).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("postgres dialect", func(t *testing.T) {
+		conn, mock := NewMock(t, db.DriverPostgres, sqlmock.QueryMatcherEqual)
+		mock.ExpectExec(`INSERT INTO "crm_shop_attach_tab" ("shop_id") VALUES (439510)`).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		if _, err := conn.NewSession().InsertInto("crm_shop_attach_tab").Pair("shop_id", 439510).Exec(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	// 未满足的期望在测试结束时报告
+	tb := &recordTB{}
+	t.Run("unmet", func(t *testing.T) {
+		tb.TB = t
+		_, mock := NewMock(tb, db.DriverMySQL, nil)
+		mock.ExpectExec("DELETE FROM crm_shop_attach_tab")
+	})
+	if len(tb.errors) != 1 {
+		t.Fatalf("expect unmet expectation reported, got %v", tb.errors)
+	}
+}
@@ -0,0 +1,84 @@
+package dbtest
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/sujunbo/micro/db"
+	"gopkg.in/yaml.v3"
+)
+
+// Fixtures 表名 -> 行, 如
+//
+//	crm_shop_attach_tab:
+//	  - {id: 1, shop_id: 439510, buyer_num: 9, is_delete: 0}
+type Fixtures map[string][]map[string]interface{}
+
+// LoadFixtures 从 .yaml/.yml/.json 文件读取并插入数据
+func LoadFixtures(tb testing.TB, conn *db.Connection, path string) {
+	tb.Helper()
+
+	buf, err := os.ReadFile(path)
+	if err != nil {
+		tb.Fatalf("dbtest read fixtures %v err:%v", path, err)
+	}
+
+	var fixtures Fixtures
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(buf, &fixtures)
+	default:
+		err = json.Unmarshal(buf, &fixtures)
+	}
+	if err != nil {
+		tb.Fatalf("dbtest parse fixtures %v err:%v", path, err)
+	}
+
+	InsertFixtures(tb, conn, fixtures)
+}
+
+// InsertFixtures 按表名顺序插入, 每行的列可以不同
+func InsertFixtures(tb testing.TB, conn *db.Connection, fixtures Fixtures) {
+	tb.Helper()
+
+	tables := make([]string, 0, len(fixtures))
+	for table := range fixtures {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	sess := conn.NewSession()
+	for _, table := range tables {
+		for _, row := range fixtures[table] {
+			columns := make([]string, 0, len(row))
+			for column := range row {
+				columns = append(columns, column)
+			}
+			sort.Strings(columns)
+
+			values := make([]interface{}, 0, len(columns))
+			for _, column := range columns {
+				values = append(values, row[column])
+			}
+
+			if _, err := sess.InsertInto(table).Columns(columns...).Values(values...).Exec(); err != nil {
+				tb.Fatalf("dbtest insert fixture %v err:%v", table, err)
+			}
+		}
+	}
+}
+
+// Exec 执行建表等原始 sql
+func Exec(tb testing.TB, conn *db.Connection, queries ...string) {
+	tb.Helper()
+
+	for _, query := range queries {
+		if _, err := conn.Exec(query); err != nil {
+			tb.Fatalf("dbtest exec %q err:%v", query, err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package dbtest
+
+import (
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujunbo/micro/db"
+)
+
+// Recorder 记录连接上执行过的 sql, 用于断言
+type Recorder struct {
+	mutex      sync.Mutex
+	statements []string
+}
+
+func NewRecorder(conn *db.Connection) *Recorder {
+	r := &Recorder{}
+	conn.AddQueryHook(func(eventName string, query string, cost time.Duration) {
+		r.mutex.Lock()
+		r.statements = append(r.statements, query)
+		r.mutex.Unlock()
+	})
+	return r
+}
+
+func (r *Recorder) Statements() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return append([]string{}, r.statements...)
+}
+
+func (r *Recorder) Reset() {
+	r.mutex.Lock()
+	r.statements = nil
+	r.mutex.Unlock()
+}
+
+// AssertExecuted 断言存在匹配正则 pattern 的 sql
+func (r *Recorder) AssertExecuted(tb testing.TB, pattern string) {
+	tb.Helper()
+
+	re := regexp.MustCompile(pattern)
+	statements := r.Statements()
+	for _, s := range statements {
+		if re.MatchString(s) {
+			return
+		}
+	}
+	tb.Errorf("dbtest no statement matches %q, executed:\n%v", pattern, strings.Join(statements, "\n"))
+}
+
+// AssertNotExecuted 断言不存在匹配正则 pattern 的 sql
+func (r *Recorder) AssertNotExecuted(tb testing.TB, pattern string) {
+	tb.Helper()
+
+	re := regexp.MustCompile(pattern)
+	for _, s := range r.Statements() {
+		if re.MatchString(s) {
+			tb.Errorf("dbtest unexpected statement matches %q: %v", pattern, s)
+		}
+	}
+}
+
+// AssertCount 断言执行的 sql 条数
+func (r *Recorder) AssertCount(tb testing.TB, n int) {
+	tb.Helper()
+
+	if statements := r.Statements(); len(statements) != n {
+		tb.Errorf("dbtest executed %v statements, want %v:\n%v", len(statements), n, strings.Join(statements, "\n"))
+	}
+}
@@ -0,0 +1,17 @@
+package dbtest
+
+import (
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// NewTracerProvider 返回同步导出到内存的 TracerProvider, 配合 db.Options.TracerProvider
+// 在测试中断言 sql span:
+//
+//	tp, exporter := dbtest.NewTracerProvider()
+//	conn := db.Open(&db.Options{..., TracerProvider: tp})
+//	spans := exporter.GetSpans()
+func NewTracerProvider() (*sdktrace.TracerProvider, *tracetest.InMemoryExporter) {
+	exporter := tracetest.NewInMemoryExporter()
+	return sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)), exporter
+}
@@ -1,15 +1,24 @@
 package db
 
 import (
+	"fmt"
 	"time"
 
+	"github.com/gocraft/dbr"
 	"github.com/gocraft/dbr/dialect"
 )
 
+const (
+	DriverMySQL    = "mysql"
+	DriverPostgres = "postgres"
+	DriverSQLite3  = "sqlite3"
+)
+
 type mysql struct{}
 
 const (
-	timeFormat = "2006-01-02 15:04:05.000000"
+	timeFormat   = "2006-01-02 15:04:05.000000"
+	pgTimeFormat = "2006-01-02 15:04:05.000000-07:00"
 )
 
 func (d mysql) QuoteIdent(s string) string {
@@ -35,3 +44,91 @@ func (d mysql) EncodeBytes(b []byte) string {
 func (d mysql) Placeholder(n int) string {
 	return dialect.MySQL.Placeholder(n)
 }
+
+type postgres struct{}
+
+func (d postgres) QuoteIdent(s string) string {
+	return dialect.PostgreSQL.QuoteIdent(s)
+}
+
+func (d postgres) EncodeString(s string) string {
+	return dialect.PostgreSQL.EncodeString(s)
+}
+
+func (d postgres) EncodeBool(b bool) string {
+	return dialect.PostgreSQL.EncodeBool(b)
+}
+
+// EncodeTime 带时区偏移, 避免 timestamptz 按会话时区解析
+func (d postgres) EncodeTime(t time.Time) string {
+	return `'` + t.Format(pgTimeFormat) + `'`
+}
+
+func (d postgres) EncodeBytes(b []byte) string {
+	return dialect.PostgreSQL.EncodeBytes(b)
+}
+
+func (d postgres) Placeholder(n int) string {
+	return dialect.PostgreSQL.Placeholder(n)
+}
+
+type sqlite3 struct{}
+
+func (d sqlite3) QuoteIdent(s string) string {
+	return dialect.SQLite3.QuoteIdent(s)
+}
+
+func (d sqlite3) EncodeString(s string) string {
+	return dialect.SQLite3.EncodeString(s)
+}
+
+func (d sqlite3) EncodeBool(b bool) string {
+	return dialect.SQLite3.EncodeBool(b)
+}
+
+// EncodeTime 与 mysql 保持一致, 使用本地时间
+func (d sqlite3) EncodeTime(t time.Time) string {
+	return `'` + t.Format(timeFormat) + `'`
+}
+
+func (d sqlite3) EncodeBytes(b []byte) string {
+	return dialect.SQLite3.EncodeBytes(b)
+}
+
+func (d sqlite3) Placeholder(n int) string {
+	return dialect.SQLite3.Placeholder(n)
+}
+
+func newDialect(driver string) (dbr.Dialect, error) {
+	switch driver {
+	case DriverMySQL:
+		return &mysql{}, nil
+	case DriverPostgres:
+		return &postgres{}, nil
+	case DriverSQLite3:
+		return &sqlite3{}, nil
+	}
+	return nil, fmt.Errorf("driver %v not support", driver)
+}
+
+// dialectDriver 返回方言对应的驱动, 兼容 dbr 自带的 dialect, 不支持时返回空串
+func dialectDriver(d dbr.Dialect) string {
+	switch d.(type) {
+	case mysql, *mysql:
+		return DriverMySQL
+	case postgres, *postgres:
+		return DriverPostgres
+	case sqlite3, *sqlite3:
+		return DriverSQLite3
+	}
+
+	switch d {
+	case dialect.MySQL:
+		return DriverMySQL
+	case dialect.PostgreSQL:
+		return DriverPostgres
+	case dialect.SQLite3:
+		return DriverSQLite3
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestPostgresDSN(t *testing.T) {
+	tests := []struct {
+		name    string
+		opt     Options
+		user    string
+		pass    string
+		sslmode string
+		params  url.Values
+	}{
+		{
+			name:    "escape password",
+			opt:     Options{UserName: "crm", Password: `p@ss w'o:rd\/?#`},
+			user:    "crm",
+			pass:    `p@ss w'o:rd\/?#`,
+			sslmode: "disable",
+		},
+		{
+			name:    "sslmode from params",
+			opt:     Options{UserName: "crm", Params: map[string]string{"sslmode": "verify-full", "application_name": "crm api"}},
+			user:    "crm",
+			sslmode: "verify-full",
+			params:  url.Values{"application_name": {"crm api"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opt := tt.opt
+			opt.Driver, opt.Host, opt.DBName = DriverPostgres, "db.local", "crm_db"
+			opt.Port = opt.defaultPort()
+
+			dsn, err := opt.dsn()
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, err := url.Parse(dsn)
+			if err != nil {
+				t.Fatalf("invalid dsn %q: %v", dsn, err)
+			}
+
+			pass, _ := u.User.Password()
+			if u.Scheme != "postgres" || u.Host != "db.local:5432" || u.User.Username() != tt.user || pass != tt.pass {
+				t.Fatalf("unexpected dsn %q", dsn)
+			}
+			if got := u.Query().Get("sslmode"); got != tt.sslmode {
+				t.Fatalf("expect sslmode %v, got %v", tt.sslmode, got)
+			}
+			for k := range tt.params {
+				if u.Query().Get(k) != tt.params.Get(k) {
+					t.Fatalf("expect param %v=%v in %q", k, tt.params.Get(k), dsn)
+				}
+			}
+
+			opt.DataSource = dsn
+			if name, _ := opt.name(); name != "crm_db" {
+				t.Fatalf("expect name crm_db, got %v", name)
+			}
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package db
+
+import (
+	"sync"
+	"time"
+)
+
+// QueryHook 每条 sql 执行完成后调用, eventName 为 dbr.select 或 dbr.exec
+type QueryHook func(eventName string, query string, cost time.Duration)
+
+type hooks struct {
+	mutex sync.RWMutex
+	list  []QueryHook
+}
+
+func (h *hooks) add(hook QueryHook) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+	h.list = append(h.list, hook)
+}
+
+func (h *hooks) run(eventName string, query string, cost time.Duration) {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+	for _, hook := range h.list {
+		hook(eventName, query, cost)
+	}
+}
+
+func (c *Connection) AddQueryHook(hook QueryHook) {
+	if r, ok := c.EventReceiver.(*sqlEventReceiver); ok {
+		r.hooks.add(hook)
+	}
+}
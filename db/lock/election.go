@@ -0,0 +1,204 @@
+package lock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/log"
+)
+
+// Elector 基于租约表的选主, 表结构:
+//
+//	CREATE TABLE `leader_lease_tab` (
+//	  `name` varchar(64) NOT NULL,
+//	  `holder` varchar(128) NOT NULL,
+//	  `expire_time` bigint NOT NULL COMMENT '租约到期时间, 毫秒',
+//	  PRIMARY KEY (`name`)
+//	) ENGINE=InnoDB;
+//
+// 到期时间均使用数据库时钟, 不受各 pod 时钟偏差影响
+type Elector struct {
+	conn *db.Connection
+	name string
+	opts options
+
+	mutex  sync.RWMutex
+	leader bool
+	cancel context.CancelFunc
+	// 本地估算的租约到期时间, 以续约请求发出的时间为起点并减去 safetyMargin
+	deadline time.Time
+	timer    *time.Timer
+}
+
+func NewElector(conn *db.Connection, name string, opts ...Option) *Elector {
+	opt := options{}
+	for _, o := range opts {
+		o(&opt)
+	}
+	opt.apply()
+
+	return &Elector{
+		conn: conn,
+		name: name,
+		opts: opt,
+	}
+}
+
+func (e *Elector) Identity() string {
+	return e.opts.identity
+}
+
+func (e *Elector) IsLeader() bool {
+	e.mutex.RLock()
+	defer e.mutex.RUnlock()
+	return e.leader && time.Now().Before(e.deadline)
+}
+
+// Run 阻塞直到 ctx 取消, 退出时主动让出 leader
+func (e *Elector) Run(ctx context.Context) {
+	tc := time.NewTicker(e.opts.heartbeat)
+	defer tc.Stop()
+
+	for {
+		e.tick(ctx)
+
+		select {
+		case <-ctx.Done():
+			e.resign()
+			return
+		case <-tc.C:
+		}
+	}
+}
+
+func (e *Elector) tick(ctx context.Context) {
+	e.expire()
+
+	// 租约从请求发出前开始计算, 早于数据库 NOW(3), 保证本地先于其他实例认为租约到期
+	start := time.Now()
+	queryCtx, cancel := context.WithTimeout(ctx, e.opts.heartbeat)
+	ok, err := e.tryAcquire(queryCtx)
+	cancel()
+	if err != nil {
+		// 续约失败, 租约到期前仍视为 leader, 到期由 timer 撤销
+		log.Errorf("lock elector name:%v identity:%v err:%v", e.name, e.opts.identity, err)
+		return
+	}
+
+	if ok {
+		e.elect(ctx, start)
+	} else {
+		e.revoke()
+	}
+}
+
+// expire 本地租约到期时撤销 leader, 由 timer 与每次心跳前调用
+func (e *Elector) expire() {
+	e.mutex.RLock()
+	expired := e.leader && !time.Now().Before(e.deadline)
+	e.mutex.RUnlock()
+
+	if expired {
+		log.Warnf("lock elector lease expired name:%v identity:%v", e.name, e.opts.identity)
+		e.revoke()
+	}
+}
+
+func (e *Elector) tryAcquire(ctx context.Context) (ok bool, err error) {
+	sess := e.conn.NewSessionContext(ctx)
+	query := fmt.Sprintf("INSERT INTO %[1]s (`name`, `holder`, `expire_time`) VALUES (?, ?, %[2]s + ?) "+
+		"ON DUPLICATE KEY UPDATE "+
+		"`holder` = IF(`expire_time` < %[2]s OR `holder` = VALUES(`holder`), VALUES(`holder`), `holder`), "+
+		"`expire_time` = IF(`holder` = VALUES(`holder`), VALUES(`expire_time`), `expire_time`)",
+		sess.QuoteIdent(e.opts.table), dbNowMillis)
+	_, err = sess.Exec(sess.InsertBySql(query, e.name, e.opts.identity, e.opts.lease.Milliseconds()))
+	if err != nil {
+		return
+	}
+
+	var holder string
+	err = sess.LoadOne(sess.Select("holder").From(e.opts.table).Where("name = ?", e.name), &holder)
+	if err != nil {
+		return
+	}
+	return holder == e.opts.identity, nil
+}
+
+const dbNowMillis = "ROUND(UNIX_TIMESTAMP(NOW(3)) * 1000)"
+
+func (e *Elector) elect(ctx context.Context, start time.Time) {
+	e.mutex.Lock()
+	e.deadline = start.Add(e.opts.lease - e.opts.safetyMargin)
+	wait := time.Until(e.deadline)
+	if wait <= 0 {
+		// 续约耗时超过租约, 本地已到期: 不成为 leader, 已是 leader 则撤销
+		e.mutex.Unlock()
+		log.Warnf("lock elector stale renewal name:%v identity:%v", e.name, e.opts.identity)
+		e.revoke()
+		return
+	}
+
+	if e.timer == nil {
+		e.timer = time.AfterFunc(wait, e.expire)
+	} else {
+		e.timer.Reset(wait)
+	}
+	if e.leader {
+		e.mutex.Unlock()
+		return
+	}
+
+	e.leader = true
+	leaderCtx, cancel := context.WithCancel(ctx)
+	e.cancel = cancel
+	e.mutex.Unlock()
+
+	log.Infof("lock elector elected name:%v identity:%v", e.name, e.opts.identity)
+	if e.opts.onElected != nil {
+		go e.opts.onElected(leaderCtx)
+	}
+}
+
+func (e *Elector) revoke() {
+	e.mutex.Lock()
+	if !e.leader {
+		e.mutex.Unlock()
+		return
+	}
+
+	e.leader = false
+	e.cancel()
+	if e.timer != nil {
+		e.timer.Stop()
+	}
+	e.mutex.Unlock()
+
+	log.Infof("lock elector revoked name:%v identity:%v", e.name, e.opts.identity)
+	if e.opts.onRevoked != nil {
+		e.opts.onRevoked()
+	}
+}
+
+// resign 将租约置为过期, 其他实例下个心跳即可接管
+func (e *Elector) resign() {
+	e.mutex.RLock()
+	leader := e.leader
+	e.mutex.RUnlock()
+	if !leader {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), e.opts.heartbeat)
+	defer cancel()
+
+	sess := e.conn.NewSessionContext(ctx)
+	_, err := sess.Exec(sess.Update(e.opts.table).Set("expire_time", 0).
+		Where("name = ? AND holder = ?", e.name, e.opts.identity))
+	if err != nil {
+		log.Errorf("lock elector resign name:%v identity:%v err:%v", e.name, e.opts.identity, err)
+	}
+	e.revoke()
+}
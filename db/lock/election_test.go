@@ -0,0 +1,169 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/db/dbtest"
+)
+
+type electorTest struct {
+	*Elector
+	mock   sqlmock.Sqlmock
+	events chan string
+	ctx    chan context.Context
+}
+
+func newElectorTest(t *testing.T, opts ...Option) *electorTest {
+	conn, mock := dbtest.NewMock(t, db.DriverMySQL, nil)
+	et := &electorTest{mock: mock, events: make(chan string, 8), ctx: make(chan context.Context, 8)}
+	opts = append([]Option{
+		WithIdentity("pod-a"),
+		WithOnElected(func(ctx context.Context) {
+			et.events <- "elected"
+			et.ctx <- ctx
+		}),
+		WithOnRevoked(func() { et.events <- "revoked" }),
+	}, opts...)
+	et.Elector = NewElector(conn, "job", opts...)
+	t.Cleanup(func() {
+		et.mutex.Lock()
+		if et.timer != nil {
+			et.timer.Stop()
+		}
+		et.mutex.Unlock()
+	})
+	return et
+}
+
+// expectRenew 续约后表中的 holder
+func (et *electorTest) expectRenew(holder string) {
+	et.mock.ExpectExec("INSERT INTO `leader_lease_tab`").WillReturnResult(sqlmock.NewResult(0, 1))
+	et.mock.ExpectQuery("SELECT holder FROM leader_lease_tab WHERE \\(name = 'job'\\)").
+		WillReturnRows(sqlmock.NewRows([]string{"holder"}).AddRow(holder))
+}
+
+func (et *electorTest) expectEvent(t *testing.T, want string, timeout time.Duration) {
+	t.Helper()
+	select {
+	case got := <-et.events:
+		if got != want {
+			t.Fatalf("expect %v, got %v", want, got)
+		}
+	case <-time.After(timeout):
+		t.Fatalf("expect %v within %v", want, timeout)
+	}
+}
+
+func (et *electorTest) expectNoEvent(t *testing.T) {
+	t.Helper()
+	select {
+	case got := <-et.events:
+		t.Fatalf("unexpected %v", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestElectorElectAndRevoke(t *testing.T) {
+	et := newElectorTest(t)
+	ctx := context.Background()
+
+	et.expectRenew("pod-a")
+	et.tick(ctx)
+	et.expectEvent(t, "elected", time.Second)
+	leaderCtx := <-et.ctx
+	if !et.IsLeader() {
+		t.Fatal("expect leader")
+	}
+
+	// 续约成功不重复回调
+	et.expectRenew("pod-a")
+	et.tick(ctx)
+	et.expectNoEvent(t)
+
+	// 租约被其他实例接管
+	et.expectRenew("pod-b")
+	et.tick(ctx)
+	et.expectEvent(t, "revoked", time.Second)
+	if et.IsLeader() {
+		t.Fatal("expect not leader")
+	}
+	if leaderCtx.Err() == nil {
+		t.Fatal("expect leader ctx canceled")
+	}
+}
+
+func TestElectorExpireOnRenewFailure(t *testing.T) {
+	lease, margin := 300*time.Millisecond, 50*time.Millisecond
+	et := newElectorTest(t, WithLease(lease), WithSafetyMargin(margin))
+	ctx := context.Background()
+
+	start := time.Now()
+	et.expectRenew("pod-a")
+	et.tick(ctx)
+	et.expectEvent(t, "elected", time.Second)
+
+	// 续约失败时租约到期前仍是 leader
+	et.mock.ExpectExec("INSERT INTO `leader_lease_tab`").WillReturnError(errors.New("connection refused"))
+	et.tick(ctx)
+	if !et.IsLeader() {
+		t.Fatal("expect leader until local deadline")
+	}
+
+	// 无需下一次心跳, 由 timer 在本地到期时撤销
+	et.expectEvent(t, "revoked", time.Second)
+	if elapsed := time.Since(start); elapsed < lease-margin {
+		t.Fatalf("revoked after %v, before local deadline %v", elapsed, lease-margin)
+	}
+	if et.IsLeader() {
+		t.Fatal("expect not leader")
+	}
+}
+
+func TestElectorStaleRenewal(t *testing.T) {
+	et := newElectorTest(t)
+	ctx := context.Background()
+
+	// 本地租约在续约返回前已到期, 不成为 leader 也不回调
+	et.elect(ctx, time.Now().Add(-et.opts.lease))
+	et.expectNoEvent(t)
+	if et.IsLeader() {
+		t.Fatal("expect not leader")
+	}
+
+	// 已是 leader 时过期的续约会撤销, OnElected 先于 OnRevoked
+	et.elect(ctx, time.Now())
+	et.expectEvent(t, "elected", time.Second)
+	et.elect(ctx, time.Now().Add(-et.opts.lease))
+	et.expectEvent(t, "revoked", time.Second)
+	et.expectNoEvent(t)
+	if et.IsLeader() {
+		t.Fatal("expect not leader")
+	}
+}
+
+func TestElectorResign(t *testing.T) {
+	et := newElectorTest(t, WithHeartbeat(time.Hour))
+	et.expectRenew("pod-a")
+	et.mock.ExpectExec("UPDATE `leader_lease_tab` SET `expire_time` = 0 WHERE \\(name = 'job' AND holder = 'pod-a'\\)").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		et.Run(ctx)
+		close(done)
+	}()
+
+	et.expectEvent(t, "elected", time.Second)
+	cancel()
+	et.expectEvent(t, "revoked", time.Second)
+	<-done
+	if et.IsLeader() {
+		t.Fatal("expect not leader")
+	}
+}
@@ -0,0 +1,96 @@
+// Package lock 基于 MySQL 的分布式锁与租约选主, 无需引入 zookeeper/etcd
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/log"
+)
+
+var (
+	ErrNotAcquired = errors.New("lock not acquired")
+	ErrNotHeld     = errors.New("lock not held")
+)
+
+// Lock 通过 GET_LOCK 获得的命名锁, 锁与会话绑定, 因此独占一个连接直到 Release
+type Lock struct {
+	name string
+	conn *sql.Conn
+}
+
+// Acquire 在 timeout 内等待获取锁, 超时返回 ErrNotAcquired
+func Acquire(ctx context.Context, c *db.Connection, name string, timeout time.Duration) (l *Lock, err error) {
+	conn, err := c.DB.Conn(ctx)
+	if err != nil {
+		return
+	}
+
+	var got sql.NullInt64
+	err = conn.QueryRowContext(ctx, "SELECT GET_LOCK(?, ?)", name, int64(timeout/time.Second)).Scan(&got)
+	if err != nil {
+		conn.Close()
+		return
+	}
+
+	if !got.Valid || got.Int64 != 1 {
+		conn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrNotAcquired, name)
+	}
+	return &Lock{name: name, conn: conn}, nil
+}
+
+// TryAcquire 不等待
+func TryAcquire(ctx context.Context, c *db.Connection, name string) (*Lock, error) {
+	return Acquire(ctx, c, name, 0)
+}
+
+func (l *Lock) Name() string {
+	return l.name
+}
+
+// Release 释放锁并归还连接; 释放失败(如 ctx 已取消, RELEASE_LOCK 未执行)时丢弃连接,
+// 避免仍持有锁的会话回到连接池, 锁随会话关闭由 MySQL 释放
+func (l *Lock) Release(ctx context.Context) (err error) {
+	defer func() {
+		if err != nil {
+			l.conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+		}
+		l.conn.Close()
+	}()
+
+	var released sql.NullInt64
+	err = l.conn.QueryRowContext(ctx, "SELECT RELEASE_LOCK(?)", l.name).Scan(&released)
+	if err != nil {
+		return
+	}
+
+	if !released.Valid || released.Int64 != 1 {
+		return fmt.Errorf("%w: %v", ErrNotHeld, l.name)
+	}
+	return
+}
+
+// Do 持有锁期间执行 fn, fn 成功时返回 Release 的错误
+func Do(ctx context.Context, c *db.Connection, name string, timeout time.Duration, fn func(ctx context.Context) error) (err error) {
+	l, err := Acquire(ctx, c, name, timeout)
+	if err != nil {
+		return
+	}
+	defer func() {
+		if rerr := l.Release(context.Background()); rerr != nil {
+			if err == nil {
+				err = rerr
+				return
+			}
+			log.Errorf("lock release name:%v err:%v", name, rerr)
+		}
+	}()
+
+	return fn(ctx)
+}
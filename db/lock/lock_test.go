@@ -0,0 +1,69 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/db/dbtest"
+)
+
+func acquire(t *testing.T) (*db.Connection, sqlmock.Sqlmock, *Lock) {
+	conn, mock := dbtest.NewMock(t, db.DriverMySQL, nil)
+	conn.DB.SetMaxIdleConns(1)
+
+	mock.ExpectQuery(`SELECT GET_LOCK`).WithArgs("job", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"got"}).AddRow(1))
+	l, err := Acquire(context.Background(), conn, "job", time.Second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, mock, l
+}
+
+func TestRelease(t *testing.T) {
+	conn, mock, l := acquire(t)
+	mock.ExpectQuery(`SELECT RELEASE_LOCK`).WithArgs("job").
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+
+	if err := l.Release(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if n := conn.DB.Stats().Idle; n != 1 {
+		t.Fatalf("expect connection back to pool, idle %d", n)
+	}
+}
+
+func TestReleaseCanceledDiscardsConn(t *testing.T) {
+	conn, mock, l := acquire(t)
+	// RELEASE_LOCK 未完成, 连接必须被关闭而不是回到连接池
+	mock.ExpectQuery(`SELECT RELEASE_LOCK`).WithArgs("job").WillDelayFor(time.Second).
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(1))
+	mock.ExpectClose()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := l.Release(ctx); err == nil {
+		t.Fatal("expect release err")
+	}
+	if n := conn.DB.Stats().OpenConnections; n != 0 {
+		t.Fatalf("expect connection discarded, open %d", n)
+	}
+}
+
+func TestDoReturnsReleaseError(t *testing.T) {
+	conn, mock := dbtest.NewMock(t, db.DriverMySQL, nil)
+	mock.ExpectQuery(`SELECT GET_LOCK`).WithArgs("job", int64(1)).
+		WillReturnRows(sqlmock.NewRows([]string{"got"}).AddRow(1))
+	mock.ExpectQuery(`SELECT RELEASE_LOCK`).WithArgs("job").
+		WillReturnRows(sqlmock.NewRows([]string{"released"}).AddRow(0))
+	mock.ExpectClose()
+
+	err := Do(context.Background(), conn, "job", time.Second, func(ctx context.Context) error { return nil })
+	if !errors.Is(err, ErrNotHeld) {
+		t.Fatalf("expect ErrNotHeld, got %v", err)
+	}
+}
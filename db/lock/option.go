@@ -0,0 +1,93 @@
+package lock
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/sujunbo/micro/util/uuid"
+)
+
+type options struct {
+	table     string
+	identity  string
+	lease     time.Duration
+	heartbeat time.Duration
+	// 本地提前于租约到期撤销 leader 的余量, 覆盖时钟频率偏差与调度延迟
+	safetyMargin time.Duration
+	onElected    func(ctx context.Context)
+	onRevoked    func()
+}
+
+func (o *options) apply() {
+	if o.table == "" {
+		o.table = "leader_lease_tab"
+	}
+
+	if o.identity == "" {
+		host, _ := os.Hostname()
+		o.identity = host + "-" + uuid.New()
+	}
+
+	if o.lease == 0 {
+		o.lease = 15 * time.Second
+	}
+
+	if o.heartbeat == 0 {
+		o.heartbeat = o.lease / 3
+	}
+
+	if o.safetyMargin <= 0 || o.safetyMargin >= o.lease {
+		o.safetyMargin = o.lease / 10
+	}
+}
+
+type Option func(*options)
+
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithIdentity 默认 hostname-uuid
+func WithIdentity(identity string) Option {
+	return func(o *options) {
+		o.identity = identity
+	}
+}
+
+// WithLease 租约时长, 默认 15s
+func WithLease(lease time.Duration) Option {
+	return func(o *options) {
+		o.lease = lease
+	}
+}
+
+// WithHeartbeat 续约间隔, 默认 lease/3
+func WithHeartbeat(heartbeat time.Duration) Option {
+	return func(o *options) {
+		o.heartbeat = heartbeat
+	}
+}
+
+// WithSafetyMargin 本地提前撤销 leader 的余量, 默认 lease/10
+func WithSafetyMargin(margin time.Duration) Option {
+	return func(o *options) {
+		o.safetyMargin = margin
+	}
+}
+
+// WithOnElected 成为 leader 时在新 goroutine 中调用, ctx 在失去 leader 时取消
+func WithOnElected(fn func(ctx context.Context)) Option {
+	return func(o *options) {
+		o.onElected = fn
+	}
+}
+
+// WithOnRevoked 失去 leader 时调用
+func WithOnRevoked(fn func()) Option {
+	return func(o *options) {
+		o.onRevoked = fn
+	}
+}
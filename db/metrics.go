@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: "db",
+		Name:      "query_duration_seconds",
+		Help:      "SQL query latency by database, operation and table.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"db", "operation", "table"})
+
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "db",
+		Name:      "query_errors_total",
+		Help:      "SQL query errors by database, operation and table.",
+	}, []string{"db", "operation", "table"})
+
+	queryTimeouts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: "db",
+		Name:      "query_timeouts_total",
+		Help:      "SQL queries aborted by deadline by database, operation and table.",
+	}, []string{"db", "operation", "table"})
+)
+
+func init() {
+	prometheus.MustRegister(queryDuration, queryErrors, queryTimeouts)
+}
+
+// queryLabels 优先使用 dbr 事件名区分 select, exec 再按 sql 解析;
+// 分表使用逻辑表名, 避免按天分表每天新增一组指标
+func queryLabels(eventName string, query string) (op, table string) {
+	op, table = parseQuery(query)
+	table = logicalTable(table)
+	if eventName == "dbr.select" && op == OpOther {
+		op = OpSelect
+	}
+
+	if table == "" {
+		table = "unknown"
+	}
+	return
+}
+
+func observeQuery(dbname string, eventName string, query string, cost time.Duration) {
+	op, table := queryLabels(eventName, query)
+	queryDuration.WithLabelValues(dbname, op, table).Observe(cost.Seconds())
+}
+
+func observeError(dbname string, eventName string, query string, err error) {
+	op, table := queryLabels(eventName, query)
+	if errors.Is(err, context.DeadlineExceeded) {
+		queryTimeouts.WithLabelValues(dbname, op, table).Inc()
+		return
+	}
+	queryErrors.WithLabelValues(dbname, op, table).Inc()
+}
@@ -0,0 +1,30 @@
+package db
+
+import "testing"
+
+func TestQueryLabels(t *testing.T) {
+	RegisterShard("label_push_data_tab", ByDay())
+	RegisterShard("label_user_tab", ByMod(16))
+	RegisterShard("label_user_tab_ext", ByMod(4))
+
+	tests := []struct {
+		event string
+		query string
+		op    string
+		table string
+	}{
+		{"dbr.select", "SELECT * FROM `label_push_data_tab_20200401` WHERE id = 1", OpSelect, "label_push_data_tab"},
+		{"dbr.exec", "UPDATE label_push_data_tab_20200402 SET a = 1", OpUpdate, "label_push_data_tab"},
+		{"dbr.exec", "INSERT INTO `db`.`label_user_tab_7` (id) VALUES (1)", OpInsert, "label_user_tab"},
+		{"dbr.exec", "DELETE FROM label_user_tab_ext_3", OpDelete, "label_user_tab_ext"},
+		{"dbr.select", "SELECT * FROM label_user_tab_backup", OpSelect, "label_user_tab_backup"},
+		{"dbr.select", "SELECT * FROM `other_tab_1`", OpSelect, "other_tab_1"},
+		{"dbr.select", "SHOW TABLES", OpSelect, "unknown"},
+	}
+	for _, tt := range tests {
+		op, table := queryLabels(tt.event, tt.query)
+		if op != tt.op || table != tt.table {
+			t.Errorf("queryLabels(%q) = %v, %v, want %v, %v", tt.query, op, table, tt.op, tt.table)
+		}
+	}
+}
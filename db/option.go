@@ -1,7 +1,18 @@
 package db
 
+import (
+	"time"
+
+	"github.com/sujunbo/micro/util/breaker"
+	"go.opentelemetry.io/otel/trace"
+)
+
 type Options struct {
-	Driver     string `default:"mysql"`
+	// 日志与指标中的库名, 默认从 DataSource 解析; Registry 中默认为注册名
+	Name string
+	// mysql(默认), postgres, sqlite3
+	Driver string
+	// 非空时忽略下面的连接参数
 	DataSource string
 	DBName     string
 	UserName   string
@@ -9,6 +20,33 @@ type Options struct {
 	Host       string
 	Port       int
 
+	// 以下仅用于 mysql, 对应 go-sql-driver/mysql Config
+	// TLS 为 true, false, skip-verify, preferred 或 mysql.RegisterTLSConfig 注册的名字
+	TLS          string
+	Timeout      time.Duration
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	// 为空时使用 charset=utf8mb4
+	Collation string
+	// time.LoadLocation 的参数, 默认 Local
+	Timezone string
+	// mysql 为 dsn 参数; postgres 为连接 url 的查询参数, 如 sslmode(默认 disable)
+	Params map[string]string
+
 	MaxIdleConns int
 	MaxOpenConns int
+	// 0 表示不限制
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// 单条查询默认超时, 0 表示不限制
+	QueryTimeout time.Duration
+
+	// 为空时使用 otel 全局 TracerProvider
+	TracerProvider trace.TracerProvider
+
+	// 可选, 对 Session 上的查询熔断, 打开时返回 ErrCircuitOpen
+	Breaker *breaker.Breaker
+	// Session 上同时执行的查询上限, 超出时返回 ErrTooManyQueries, 0 表示不限制
+	MaxInFlight int
 }
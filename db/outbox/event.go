@@ -0,0 +1,33 @@
+package outbox
+
+const (
+	StatusPending = 0
+	StatusDone    = 1
+)
+
+// Event outbox 表中的一行:
+//
+//	CREATE TABLE `outbox_event_tab` (
+//	  `id` bigint unsigned NOT NULL AUTO_INCREMENT,
+//	  `topic` varchar(128) NOT NULL,
+//	  `payload` mediumblob NOT NULL,
+//	  `status` tinyint NOT NULL DEFAULT 0,
+//	  `attempts` int NOT NULL DEFAULT 0,
+//	  `next_time` bigint NOT NULL DEFAULT 0,
+//	  `ctime` bigint NOT NULL,
+//	  `mtime` bigint NOT NULL,
+//	  PRIMARY KEY (`id`),
+//	  KEY `idx_status_next_time` (`status`, `next_time`)
+//	) ENGINE=InnoDB;
+type Event struct {
+	ID       int64  `db:"id"`
+	Topic    string `db:"topic"`
+	Payload  []byte `db:"payload"`
+	Status   int    `db:"status"`
+	Attempts int    `db:"attempts"`
+	NextTime int64  `db:"next_time"`
+	Ctime    int64  `db:"ctime"`
+	Mtime    int64  `db:"mtime"`
+}
+
+var columns = []string{"id", "topic", "payload", "status", "attempts", "next_time", "ctime", "mtime"}
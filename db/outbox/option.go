@@ -0,0 +1,75 @@
+package outbox
+
+import "time"
+
+type options struct {
+	table      string
+	interval   time.Duration
+	batchSize  uint64
+	minBackoff time.Duration
+	maxBackoff time.Duration
+	retention  time.Duration
+}
+
+func (o *options) apply() {
+	if o.table == "" {
+		o.table = "outbox_event_tab"
+	}
+
+	if o.interval == 0 {
+		o.interval = time.Second
+	}
+
+	if o.batchSize == 0 {
+		o.batchSize = 100
+	}
+
+	if o.minBackoff == 0 {
+		o.minBackoff = time.Second
+	}
+
+	if o.maxBackoff == 0 {
+		o.maxBackoff = 10 * time.Minute
+	}
+
+	if o.retention == 0 {
+		o.retention = 7 * 24 * time.Hour
+	}
+}
+
+type Option func(*options)
+
+func WithTable(table string) Option {
+	return func(o *options) {
+		o.table = table
+	}
+}
+
+// WithInterval 轮询间隔, 默认 1s
+func WithInterval(interval time.Duration) Option {
+	return func(o *options) {
+		o.interval = interval
+	}
+}
+
+// WithBatchSize 每次轮询最多投递的事件数, 默认 100
+func WithBatchSize(size uint64) Option {
+	return func(o *options) {
+		o.batchSize = size
+	}
+}
+
+// WithBackoff 失败重试的指数退避区间, 默认 1s ~ 10m
+func WithBackoff(min, max time.Duration) Option {
+	return func(o *options) {
+		o.minBackoff = min
+		o.maxBackoff = max
+	}
+}
+
+// WithRetention 已投递事件的保留时长, 默认 7d
+func WithRetention(retention time.Duration) Option {
+	return func(o *options) {
+		o.retention = retention
+	}
+}
@@ -0,0 +1,152 @@
+// Package outbox 事务性发件箱: 事件与业务数据在同一事务中写入, 由 relay 异步至少一次投递
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/log"
+)
+
+type Outbox struct {
+	conn      *db.Connection
+	publisher Publisher
+	opts      options
+}
+
+func New(conn *db.Connection, publisher Publisher, opts ...Option) *Outbox {
+	opt := options{}
+	for _, o := range opts {
+		o(&opt)
+	}
+	opt.apply()
+
+	return &Outbox{
+		conn:      conn,
+		publisher: publisher,
+		opts:      opt,
+	}
+}
+
+// Add 在业务事务 tx 中写入事件, payload 为 []byte 时原样写入, 否则 json 编码
+func (o *Outbox) Add(ctx context.Context, tx *dbr.Tx, topic string, payload interface{}) (err error) {
+	buf, ok := payload.([]byte)
+	if !ok {
+		if buf, err = json.Marshal(payload); err != nil {
+			return
+		}
+	}
+
+	now := time.Now().Unix()
+	_, err = tx.InsertInto(o.opts.table).
+		Columns("topic", "payload", "status", "attempts", "next_time", "ctime", "mtime").
+		Values(topic, buf, StatusPending, 0, 0, now, now).
+		ExecContext(ctx)
+	return
+}
+
+// Run relay 主循环, 阻塞直到 ctx 取消; 多实例部署时建议配合 db/lock.Elector 只在 leader 上运行
+func (o *Outbox) Run(ctx context.Context) {
+	tc := time.NewTicker(o.opts.interval)
+	defer tc.Stop()
+
+	cleanup := time.NewTicker(time.Hour)
+	defer cleanup.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-tc.C:
+			o.drain(ctx)
+		case <-cleanup.C:
+			if err := o.cleanup(ctx); err != nil {
+				log.Errorf("outbox cleanup table:%v err:%v", o.opts.table, err)
+			}
+		}
+	}
+}
+
+// drain 连续投递直到某一批不满或出错
+func (o *Outbox) drain(ctx context.Context) {
+	for {
+		n, err := o.relay(ctx)
+		if err != nil {
+			log.Errorf("outbox relay table:%v err:%v", o.opts.table, err)
+		}
+		if err != nil || uint64(n) < o.opts.batchSize {
+			return
+		}
+	}
+}
+
+// relay 投递一批到期的事件, 返回本批数量
+func (o *Outbox) relay(ctx context.Context) (n int, err error) {
+	sess := o.conn.NewSessionContext(ctx)
+
+	var events []*Event
+	stmt := sess.Select(columns...).From(o.opts.table).
+		Where("status = ? AND next_time <= ?", StatusPending, time.Now().Unix()).
+		OrderAsc("id").
+		Limit(o.opts.batchSize)
+	if n, err = sess.Load(stmt, &events); err != nil {
+		return
+	}
+
+	for _, e := range events {
+		if ctx.Err() != nil {
+			return n, ctx.Err()
+		}
+
+		if perr := o.publisher.Publish(ctx, e); perr != nil {
+			log.Warnf("outbox publish id:%v topic:%v attempts:%v err:%v", e.ID, e.Topic, e.Attempts, perr)
+			err = o.retry(sess, e)
+		} else {
+			err = o.done(sess, e)
+		}
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+func (o *Outbox) done(sess *db.Session, e *Event) (err error) {
+	_, err = sess.Exec(sess.Update(o.opts.table).
+		Set("status", StatusDone).
+		Set("mtime", time.Now().Unix()).
+		Where("id = ?", e.ID))
+	return
+}
+
+func (o *Outbox) retry(sess *db.Session, e *Event) (err error) {
+	now := time.Now()
+	_, err = sess.Exec(sess.Update(o.opts.table).
+		Set("attempts", e.Attempts+1).
+		Set("next_time", now.Add(o.backoff(e.Attempts)).Unix()).
+		Set("mtime", now.Unix()).
+		Where("id = ?", e.ID))
+	return
+}
+
+func (o *Outbox) backoff(attempts int) time.Duration {
+	d := o.opts.minBackoff
+	for i := 0; i < attempts && d < o.opts.maxBackoff; i++ {
+		d *= 2
+	}
+	if d > o.opts.maxBackoff {
+		d = o.opts.maxBackoff
+	}
+	return d
+}
+
+// cleanup 删除超过保留期的已投递事件
+func (o *Outbox) cleanup(ctx context.Context) (err error) {
+	sess := o.conn.NewSessionContext(ctx)
+	_, err = sess.Exec(sess.DeleteFrom(o.opts.table).
+		Where("status = ? AND mtime < ?", StatusDone, time.Now().Add(-o.opts.retention).Unix()))
+	return
+}
@@ -0,0 +1,219 @@
+package outbox
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sujunbo/micro/db/dbtest"
+)
+
+const schema = `CREATE TABLE outbox_event_tab (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	topic TEXT NOT NULL,
+	payload BLOB NOT NULL,
+	status INTEGER NOT NULL DEFAULT 0,
+	attempts INTEGER NOT NULL DEFAULT 0,
+	next_time INTEGER NOT NULL DEFAULT 0,
+	ctime INTEGER NOT NULL,
+	mtime INTEGER NOT NULL
+)`
+
+// fakePublisher 记录投递的事件, err 不为空时投递失败
+type fakePublisher struct {
+	mutex  sync.Mutex
+	err    error
+	events []*Event
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, e *Event) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.err != nil {
+		return p.err
+	}
+	p.events = append(p.events, e)
+	return nil
+}
+
+func newOutbox(t *testing.T, opts ...Option) (*Outbox, *fakePublisher) {
+	conn := dbtest.NewSQLite(t)
+	if _, err := conn.Exec(schema); err != nil {
+		t.Fatal(err)
+	}
+
+	p := &fakePublisher{}
+	return New(conn, p, opts...), p
+}
+
+func addEvents(t *testing.T, o *Outbox, n int) {
+	t.Helper()
+	sess := o.conn.NewSessionContext(context.Background())
+	tx, err := sess.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.RollbackUnlessCommitted()
+
+	for i := 0; i < n; i++ {
+		if err = o.Add(context.Background(), tx, "order.created", map[string]int{"order_id": i}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func loadEvents(t *testing.T, o *Outbox) (events []*Event) {
+	t.Helper()
+	sess := o.conn.NewSessionContext(context.Background())
+	if _, err := sess.Load(sess.Select(columns...).From(o.opts.table).OrderAsc("id"), &events); err != nil {
+		t.Fatal(err)
+	}
+	return
+}
+
+func TestAddRollback(t *testing.T) {
+	o, _ := newOutbox(t)
+	sess := o.conn.NewSessionContext(context.Background())
+
+	tx, err := sess.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = o.Add(context.Background(), tx, "order.created", []byte(`{"order_id":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err = tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if events := loadEvents(t, o); len(events) != 0 {
+		t.Fatalf("expect no event after rollback, got %d", len(events))
+	}
+
+	addEvents(t, o, 1)
+	events := loadEvents(t, o)
+	if len(events) != 1 || events[0].Status != StatusPending || string(events[0].Payload) != `{"order_id":0}` {
+		t.Fatalf("expect 1 pending event after commit, got %+v", events)
+	}
+}
+
+func TestRelayDone(t *testing.T) {
+	o, p := newOutbox(t)
+	addEvents(t, o, 2)
+
+	n, err := o.relay(context.Background())
+	if err != nil || n != 2 {
+		t.Fatalf("expect 2 relayed, got %d %v", n, err)
+	}
+	if len(p.events) != 2 || p.events[0].Topic != "order.created" {
+		t.Fatalf("unexpected published %+v", p.events)
+	}
+	for _, e := range loadEvents(t, o) {
+		if e.Status != StatusDone || e.Attempts != 0 {
+			t.Fatalf("expect done, got %+v", e)
+		}
+	}
+
+	// 已投递的不再投递
+	if n, err = o.relay(context.Background()); err != nil || n != 0 {
+		t.Fatalf("expect nothing to relay, got %d %v", n, err)
+	}
+}
+
+func TestRelayRetry(t *testing.T) {
+	o, p := newOutbox(t, WithBackoff(time.Minute, 10*time.Minute))
+	addEvents(t, o, 1)
+	p.err = errors.New("broker unavailable")
+
+	now := time.Now().Unix()
+	if _, err := o.relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e := loadEvents(t, o)[0]
+	if e.Status != StatusPending || e.Attempts != 1 {
+		t.Fatalf("expect pending with 1 attempt, got %+v", e)
+	}
+	if e.NextTime < now+60 || e.NextTime > now+61 {
+		t.Fatalf("expect next_time now+60s, got %d (now %d)", e.NextTime, now)
+	}
+
+	// 未到 next_time 不投递
+	p.err = nil
+	if n, err := o.relay(context.Background()); err != nil || n != 0 {
+		t.Fatalf("expect nothing due, got %d %v", n, err)
+	}
+
+	// 多次失败后退避封顶
+	sess := o.conn.NewSessionContext(context.Background())
+	if _, err := sess.Exec(sess.Update(o.opts.table).Set("attempts", 20).Set("next_time", 0)); err != nil {
+		t.Fatal(err)
+	}
+	p.err = errors.New("broker unavailable")
+	now = time.Now().Unix()
+	if _, err := o.relay(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	e = loadEvents(t, o)[0]
+	if e.Attempts != 21 || e.NextTime < now+600 || e.NextTime > now+601 {
+		t.Fatalf("expect attempts 21 and next_time capped at now+10m, got %+v (now %d)", e, now)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	o := New(nil, nil, WithBackoff(time.Second, 10*time.Second))
+	for attempts, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		if got := o.backoff(attempts); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+	if got := o.backoff(1000); got != 10*time.Second {
+		t.Errorf("backoff(1000) = %v, want cap", got)
+	}
+}
+
+func TestDrainFullBatch(t *testing.T) {
+	o, p := newOutbox(t, WithBatchSize(2))
+	addEvents(t, o, 5)
+
+	o.drain(context.Background())
+	if len(p.events) != 5 {
+		t.Fatalf("expect all 5 events relayed in one drain, got %d", len(p.events))
+	}
+}
+
+func TestCleanup(t *testing.T) {
+	o, _ := newOutbox(t, WithRetention(time.Hour))
+
+	old := time.Now().Add(-2 * time.Hour).Unix()
+	recent := time.Now().Add(-time.Minute).Unix()
+	sess := o.conn.NewSessionContext(context.Background())
+	for _, e := range []Event{
+		{Topic: "old.done", Status: StatusDone, Mtime: old},
+		{Topic: "recent.done", Status: StatusDone, Mtime: recent},
+		{Topic: "old.pending", Status: StatusPending, Mtime: old},
+	} {
+		_, err := sess.Exec(sess.InsertInto(o.opts.table).
+			Columns("topic", "payload", "status", "attempts", "next_time", "ctime", "mtime").
+			Values(e.Topic, []byte("{}"), e.Status, 0, 0, e.Mtime, e.Mtime))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := o.cleanup(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	var topics []string
+	for _, e := range loadEvents(t, o) {
+		topics = append(topics, e.Topic)
+	}
+	if len(topics) != 2 || topics[0] != "recent.done" || topics[1] != "old.pending" {
+		t.Fatalf("expect recent.done and old.pending kept, got %v", topics)
+	}
+}
@@ -0,0 +1,49 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+// Publisher 投递事件, 返回 nil 视为投递成功; 可能重复投递, 消费方需幂等
+type Publisher interface {
+	Publish(ctx context.Context, e *Event) error
+}
+
+type PublisherFunc func(ctx context.Context, e *Event) error
+
+func (f PublisherFunc) Publish(ctx context.Context, e *Event) error {
+	return f(ctx, e)
+}
+
+type webhookPublisher struct {
+	client *xhttp.HttpClient
+	url    string
+}
+
+// NewWebhookPublisher 以 POST json 的方式投递到 url, 2xx 视为成功
+func NewWebhookPublisher(client *xhttp.HttpClient, url string) Publisher {
+	return &webhookPublisher{client: client, url: url}
+}
+
+type webhookBody struct {
+	ID       int64       `json:"id"`
+	Topic    string      `json:"topic"`
+	Payload  interface{} `json:"payload"`
+	Attempts int         `json:"attempts"`
+	Ctime    int64       `json:"ctime"`
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, e *Event) error {
+	body := webhookBody{ID: e.ID, Topic: e.Topic, Payload: e.Payload, Attempts: e.Attempts, Ctime: e.Ctime}
+	// json payload 原样透传, 否则按 base64 编码
+	if json.Valid(e.Payload) {
+		body.Payload = json.RawMessage(e.Payload)
+	}
+
+	return p.client.Post(ctx, p.url, body, nil,
+		xhttp.WithHeaderSet("X-Outbox-Topic", e.Topic, "X-Outbox-Id", fmt.Sprintf("%d", e.ID)))
+}
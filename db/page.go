@@ -0,0 +1,198 @@
+package db
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gocraft/dbr"
+)
+
+var ErrInvalidCursor = errors.New("invalid page cursor")
+
+// DefaultPageLimit Page.Limit 为 0 时的每页条数
+const DefaultPageLimit = 20
+
+// Page 基于唯一有序列的 keyset 分页, 替代深度 OFFSET:
+//
+//	p := db.Page{Column: "id", Limit: 100, Cursor: req.Cursor}
+//	stmt, err := p.Apply(sess.Select("*").From("push_data_tab"))
+//	n, err := stmt.LoadContext(ctx, &list)
+//	next, err := p.Next(list)
+type Page struct {
+	Column string
+	Desc   bool
+	// 每页条数, 为 0 时使用 DefaultPageLimit
+	Limit uint64
+	// 上一页返回的游标, 首页为空
+	Cursor string
+}
+
+type cursor struct {
+	Column string      `json:"c"`
+	Value  interface{} `json:"v"`
+}
+
+func (p Page) Apply(stmt *dbr.SelectStmt) (*dbr.SelectStmt, error) {
+	if p.Cursor != "" {
+		last, err := p.decode()
+		if err != nil {
+			return nil, err
+		}
+
+		if p.Desc {
+			stmt.Where(dbr.Lt(p.Column, last))
+		} else {
+			stmt.Where(dbr.Gt(p.Column, last))
+		}
+	}
+	return stmt.OrderDir(p.Column, !p.Desc).Limit(p.limit()), nil
+}
+
+func (p Page) limit() uint64 {
+	if p.Limit == 0 {
+		return DefaultPageLimit
+	}
+	return p.Limit
+}
+
+// Next 根据本页结果(结构体切片)生成下一页游标, 没有更多数据时返回空串
+func (p Page) Next(list interface{}) (string, error) {
+	v := reflect.Indirect(reflect.ValueOf(list))
+	if v.Kind() != reflect.Slice {
+		return "", fmt.Errorf("page list must be slice, got %T", list)
+	}
+
+	if v.Len() == 0 || uint64(v.Len()) < p.limit() {
+		return "", nil
+	}
+
+	last, err := columnValue(v.Index(v.Len()-1), p.Column)
+	if err != nil {
+		return "", err
+	}
+	return EncodeCursor(p.Column, last)
+}
+
+func (p Page) decode() (interface{}, error) {
+	buf, err := base64.RawURLEncoding.DecodeString(p.Cursor)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c cursor
+	d := json.NewDecoder(strings.NewReader(string(buf)))
+	d.UseNumber()
+	if err = d.Decode(&c); err != nil || c.Column != p.Column {
+		return nil, ErrInvalidCursor
+	}
+
+	if n, ok := c.Value.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return i, nil
+		}
+		return n.Float64()
+	}
+	return c.Value, nil
+}
+
+// EncodeCursor 生成对外暴露的不透明游标
+func EncodeCursor(column string, value interface{}) (string, error) {
+	buf, err := json.Marshal(cursor{Column: column, Value: value})
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// columnValue 按 db tag(或字段名的 snake_case)取结构体中的列值
+func columnValue(v reflect.Value, column string) (interface{}, error) {
+	v = reflect.Indirect(v)
+	if v.Kind() == reflect.Map {
+		if mv := v.MapIndex(reflect.ValueOf(column)); mv.IsValid() {
+			return mv.Interface(), nil
+		}
+		return nil, fmt.Errorf("column %v not found", column)
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("column %v not found in %v", column, v.Type())
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if sf.Tag.Get("db") == column || strings.EqualFold(sf.Name, strings.Replace(column, "_", "", -1)) {
+			return v.Field(i).Interface(), nil
+		}
+	}
+	return nil, fmt.Errorf("column %v not found in %v", column, t)
+}
+
+// Rows 逐行读取查询结果, 内存占用与结果集大小无关, ctx 取消后停止迭代
+type Rows struct {
+	ctx context.Context
+	it  dbr.Iterator
+	err error
+}
+
+func Iterate(ctx context.Context, stmt *dbr.SelectStmt) (*Rows, error) {
+	it, err := stmt.IterateContext(ctx)
+	if err != nil {
+		return nil, ctxError(err)
+	}
+	return &Rows{ctx: ctx, it: it}, nil
+}
+
+func (r *Rows) Next() bool {
+	if r.err != nil {
+		return false
+	}
+
+	if err := r.ctx.Err(); err != nil {
+		r.err = ctxError(err)
+		return false
+	}
+	return r.it.Next()
+}
+
+func (r *Rows) Scan(value interface{}) error {
+	return r.it.Scan(value)
+}
+
+func (r *Rows) Err() error {
+	if r.err != nil {
+		return r.err
+	}
+	return ctxError(r.it.Err())
+}
+
+func (r *Rows) Close() error {
+	return r.it.Close()
+}
+
+// Each 对每一行调用 fn, value 在每次调用前被重置
+func Each(ctx context.Context, stmt *dbr.SelectStmt, value interface{}, fn func() error) (err error) {
+	rows, err := Iterate(ctx, stmt)
+	if err != nil {
+		return
+	}
+	defer rows.Close()
+
+	v := reflect.ValueOf(value).Elem()
+	zero := reflect.Zero(v.Type())
+	for rows.Next() {
+		v.Set(zero)
+		if err = rows.Scan(value); err != nil {
+			return
+		}
+		if err = fn(); err != nil {
+			return
+		}
+	}
+	return rows.Err()
+}
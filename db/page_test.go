@@ -0,0 +1,57 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gocraft/dbr"
+	"github.com/gocraft/dbr/dialect"
+)
+
+func TestPageDefaultLimit(t *testing.T) {
+	tests := []struct {
+		limit uint64
+		want  string
+	}{
+		{0, "LIMIT 20"},
+		{100, "LIMIT 100"},
+	}
+	for _, tt := range tests {
+		p := Page{Column: "id", Limit: tt.limit}
+		stmt, err := p.Apply(dbr.Select("*").From("push_data_tab"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buf := dbr.NewBuffer()
+		if err = stmt.Build(dialect.MySQL, buf); err != nil {
+			t.Fatal(err)
+		}
+		if !strings.HasSuffix(buf.String(), tt.want) {
+			t.Errorf("Limit %d: got %q, want suffix %q", tt.limit, buf.String(), tt.want)
+		}
+	}
+}
+
+func TestPageNext(t *testing.T) {
+	type row struct {
+		ID int64 `db:"id"`
+	}
+
+	p := Page{Column: "id"}
+	if next, err := p.Next(make([]row, DefaultPageLimit-1)); err != nil || next != "" {
+		t.Fatalf("short page: next = %q, %v", next, err)
+	}
+
+	list := make([]row, DefaultPageLimit)
+	list[len(list)-1].ID = 42
+	next, err := p.Next(list)
+	if err != nil || next == "" {
+		t.Fatalf("full page: next = %q, %v", next, err)
+	}
+
+	p.Cursor = next
+	if last, err := p.decode(); err != nil || last != int64(42) {
+		t.Fatalf("decode = %v, %v", last, err)
+	}
+}
@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+)
+
+// Registry 按名字管理多个库的连接, 名字同时作为日志与指标中的库名
+type Registry struct {
+	mutex sync.RWMutex
+	conns map[string]*Connection
+}
+
+func NewRegistry() *Registry {
+	return &Registry{conns: map[string]*Connection{}}
+}
+
+// Open 打开 configs 中的全部连接, 任一失败时关闭已打开的连接并返回错误
+func (r *Registry) Open(configs map[string]*Options) (err error) {
+	opened := map[string]*Connection{}
+	for name, opt := range configs {
+		if opt.Name == "" {
+			opt.Name = name
+		}
+
+		c, err := New(opt)
+		if err != nil {
+			for _, c := range opened {
+				c.Close()
+			}
+			return fmt.Errorf("db registry open %v err:%w", name, err)
+		}
+		opened[name] = c
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name, c := range opened {
+		if old, ok := r.conns[name]; ok {
+			old.Close()
+		}
+		r.conns[name] = c
+	}
+	return
+}
+
+func (r *Registry) Add(name string, c *Connection) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.conns[name] = c
+}
+
+func (r *Registry) Lookup(name string) (c *Connection, ok bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	c, ok = r.conns[name]
+	return
+}
+
+// Get 未注册时 panic
+func (r *Registry) Get(name string) *Connection {
+	c, ok := r.Lookup(name)
+	if !ok {
+		panic(fmt.Sprintf("db %v not registered", name))
+	}
+	return c
+}
+
+func (r *Registry) Names() (names []string) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	for name := range r.conns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Close 关闭全部连接, 返回第一个错误
+func (r *Registry) Close() (err error) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	for name, c := range r.conns {
+		if cerr := c.Close(); cerr != nil && err == nil {
+			err = fmt.Errorf("db registry close %v err:%w", name, cerr)
+		}
+		delete(r.conns, name)
+	}
+	return
+}
+
+// Health ping 全部连接, 返回失败的连接及错误
+func (r *Registry) Health(ctx context.Context) map[string]error {
+	r.mutex.RLock()
+	conns := make(map[string]*Connection, len(r.conns))
+	for name, c := range r.conns {
+		conns[name] = c
+	}
+	r.mutex.RUnlock()
+
+	var (
+		mutex sync.Mutex
+		wg    sync.WaitGroup
+		errs  = map[string]error{}
+	)
+	for name, c := range conns {
+		wg.Add(1)
+		go func(name string, c *Connection) {
+			defer wg.Done()
+			if err := c.PingContext(ctx); err != nil {
+				mutex.Lock()
+				errs[name] = err
+				mutex.Unlock()
+			}
+		}(name, c)
+	}
+	wg.Wait()
+	return errs
+}
+
+// HealthHandler 全部健康时返回 200, 否则 503, body 为 {"name": "ok" | "err"}
+func (r *Registry) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		errs := r.Health(req.Context())
+		status := map[string]string{}
+		for _, name := range r.Names() {
+			status[name] = "ok"
+			if err, ok := errs[name]; ok {
+				status[name] = err.Error()
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		if len(errs) > 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+		json.NewEncoder(w).Encode(status)
+	})
+}
+
+var defaultRegistry = NewRegistry()
+
+func DefaultRegistry() *Registry {
+	return defaultRegistry
+}
+
+// Init 在默认 Registry 中打开 configs 中的连接
+func Init(configs map[string]*Options) error {
+	return defaultRegistry.Open(configs)
+}
+
+// Get 从默认 Registry 取连接, 如 db.Get("orders")
+func Get(name string) *Connection {
+	return defaultRegistry.Get(name)
+}
+
+func CloseAll() error {
+	return defaultRegistry.Close()
+}
+
+func Health(ctx context.Context) map[string]error {
+	return defaultRegistry.Health(ctx)
+}
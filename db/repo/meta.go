@@ -0,0 +1,134 @@
+package repo
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+const (
+	columnIsDelete = "is_delete"
+	columnCtime    = "ctime"
+	columnMtime    = "mtime"
+)
+
+type field struct {
+	column string
+	index  []int
+}
+
+// meta 由结构体 db tag 解析出的表结构
+type meta struct {
+	fields  []field
+	columns []string
+	byName  map[string]field
+}
+
+func newMeta(t reflect.Type) *meta {
+	if t.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("repo: %v is not a struct", t))
+	}
+
+	m := &meta{byName: map[string]field{}}
+	m.parse(t, nil)
+	return m
+}
+
+func (m *meta) parse(t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		idx := append(append([]int{}, index...), i)
+		if sf.Anonymous && sf.Type.Kind() == reflect.Struct && sf.Tag.Get("db") == "" {
+			m.parse(sf.Type, idx)
+			continue
+		}
+
+		if sf.PkgPath != "" {
+			continue
+		}
+
+		column := sf.Tag.Get("db")
+		if column == "-" {
+			continue
+		}
+		if column == "" {
+			column = camelCaseToSnakeCase(sf.Name)
+		}
+
+		f := field{column: column, index: idx}
+		m.fields = append(m.fields, f)
+		m.columns = append(m.columns, column)
+		m.byName[column] = f
+	}
+}
+
+func (m *meta) has(column string) bool {
+	_, ok := m.byName[column]
+	return ok
+}
+
+func (m *meta) value(v reflect.Value, column string) reflect.Value {
+	return v.FieldByIndex(m.byName[column].index)
+}
+
+// columnsExcept 返回除 except 外的所有列
+func (m *meta) columnsExcept(except string) (columns []string) {
+	for _, c := range m.columns {
+		if c != except {
+			columns = append(columns, c)
+		}
+	}
+	return
+}
+
+// touch 填充 ctime/mtime, 支持 int 系列(unix 秒)与 time.Time
+func (m *meta) touch(v reflect.Value, columns ...string) {
+	now := time.Now()
+	for _, c := range columns {
+		if !m.has(c) {
+			continue
+		}
+		setTime(m.value(v, c), now)
+	}
+}
+
+func setTime(fv reflect.Value, now time.Time) {
+	switch fv.Kind() {
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		fv.SetInt(now.Unix())
+	case reflect.Uint, reflect.Uint32, reflect.Uint64:
+		fv.SetUint(uint64(now.Unix()))
+	default:
+		if fv.Type() == reflect.TypeOf(now) {
+			fv.Set(reflect.ValueOf(now))
+		}
+	}
+}
+
+func timeValue(fv reflect.Value, now time.Time) interface{} {
+	nv := reflect.New(fv.Type()).Elem()
+	setTime(nv, now)
+	return nv.Interface()
+}
+
+// 与 dbr 默认列名规则一致: ShopID -> shop_id
+func camelCaseToSnakeCase(name string) string {
+	var buf strings.Builder
+	runes := []rune(name)
+	for i, r := range runes {
+		isUpper := r >= 'A' && r <= 'Z'
+		if isUpper && i > 0 {
+			prevLower := runes[i-1] >= 'a' && runes[i-1] <= 'z'
+			nextLower := i+1 < len(runes) && runes[i+1] >= 'a' && runes[i+1] <= 'z'
+			if prevLower || (nextLower && runes[i-1] >= 'A' && runes[i-1] <= 'Z') {
+				buf.WriteByte('_')
+			}
+		}
+		if isUpper {
+			r += 'a' - 'A'
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
@@ -0,0 +1,30 @@
+package repo
+
+type options struct {
+	primaryKey string
+	chunkSize  int
+	softDelete bool
+}
+
+type Option func(*options)
+
+// WithPrimaryKey 默认 id
+func WithPrimaryKey(column string) Option {
+	return func(o *options) {
+		o.primaryKey = column
+	}
+}
+
+// WithChunkSize BatchInsert 每条 INSERT 的最大行数, 默认 500, 小于等于 0 时使用默认值
+func WithChunkSize(size int) Option {
+	return func(o *options) {
+		o.chunkSize = size
+	}
+}
+
+// WithHardDelete 即使存在 is_delete 列也物理删除
+func WithHardDelete() Option {
+	return func(o *options) {
+		o.softDelete = false
+	}
+}
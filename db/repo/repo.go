@@ -0,0 +1,235 @@
+// Package repo 基于 dbr 与结构体 db tag 的通用表操作,
+// 约定表包含自增 id, 以及可选的 is_delete/ctime/mtime 列
+package repo
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/gocraft/dbr"
+	"github.com/sujunbo/micro/db"
+)
+
+// ErrMixedPrimaryKey BatchInsert 同一批中部分行设置了主键, 部分为零值
+var ErrMixedPrimaryKey = errors.New("repo batch insert mixes zero and non-zero primary keys")
+
+type Repo[T any] struct {
+	conn  *db.Connection
+	table string
+	meta  *meta
+	opts  options
+}
+
+type Query struct {
+	Where  []dbr.Builder
+	Order  []Order
+	Limit  uint64
+	Offset uint64
+	// 是否包含已软删除的行
+	WithDeleted bool
+}
+
+type Order struct {
+	Column string
+	Desc   bool
+}
+
+func New[T any](conn *db.Connection, table string, opts ...Option) *Repo[T] {
+	opt := options{
+		primaryKey: "id",
+		chunkSize:  500,
+		softDelete: true,
+	}
+	for _, o := range opts {
+		o(&opt)
+	}
+	if opt.chunkSize <= 0 {
+		opt.chunkSize = 500
+	}
+
+	m := newMeta(reflect.TypeOf((*T)(nil)).Elem())
+	opt.softDelete = opt.softDelete && m.has(columnIsDelete)
+	return &Repo[T]{
+		conn:  conn,
+		table: table,
+		meta:  m,
+		opts:  opt,
+	}
+}
+
+func (r *Repo[T]) Table() string {
+	return r.table
+}
+
+func (r *Repo[T]) session(ctx context.Context) *db.Session {
+	return r.conn.NewSessionContext(ctx)
+}
+
+func (r *Repo[T]) alive(stmt *dbr.SelectStmt) *dbr.SelectStmt {
+	if r.opts.softDelete {
+		stmt.Where(dbr.Eq(columnIsDelete, 0))
+	}
+	return stmt
+}
+
+// Get 按主键查询, 不存在时返回 dbr.ErrNotFound
+func (r *Repo[T]) Get(ctx context.Context, id interface{}) (*T, error) {
+	sess := r.session(ctx)
+	stmt := sess.Select(r.meta.columns...).From(r.table).Where(dbr.Eq(r.opts.primaryKey, id))
+
+	v := new(T)
+	if err := sess.LoadOne(r.alive(stmt), v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+func (r *Repo[T]) List(ctx context.Context, q Query) (list []*T, err error) {
+	sess := r.session(ctx)
+	stmt := sess.Select(r.meta.columns...).From(r.table)
+	for _, w := range q.Where {
+		stmt.Where(w)
+	}
+	if !q.WithDeleted {
+		r.alive(stmt)
+	}
+	for _, o := range q.Order {
+		stmt.OrderDir(o.Column, !o.Desc)
+	}
+	if q.Limit > 0 {
+		stmt.Limit(q.Limit)
+	}
+	if q.Offset > 0 {
+		stmt.Offset(q.Offset)
+	}
+
+	_, err = sess.Load(stmt, &list)
+	return
+}
+
+// Insert 自动填充 ctime/mtime; 主键为零值时视为自增, 不写入并将自增 id 回写到 v
+func (r *Repo[T]) Insert(ctx context.Context, v *T) (err error) {
+	rv := reflect.ValueOf(v).Elem()
+	r.meta.touch(rv, columnCtime, columnMtime)
+
+	columns := r.meta.columns
+	if r.zeroKey(rv) {
+		columns = r.meta.columnsExcept(r.opts.primaryKey)
+	}
+
+	sess := r.session(ctx)
+	_, err = sess.Exec(sess.InsertInto(r.table).Columns(columns...).Record(v))
+	return
+}
+
+// zeroKey 主键列不存在或为零值
+func (r *Repo[T]) zeroKey(rv reflect.Value) bool {
+	return !r.meta.has(r.opts.primaryKey) || r.meta.value(rv, r.opts.primaryKey).IsZero()
+}
+
+// BatchInsert 按 WithChunkSize 分批插入, 不回写 id; 主键需全部为零值(自增)或全部已设置
+func (r *Repo[T]) BatchInsert(ctx context.Context, list []*T) (err error) {
+	if len(list) == 0 {
+		return
+	}
+
+	zero := r.zeroKey(reflect.ValueOf(list[0]).Elem())
+	for _, v := range list[1:] {
+		if r.zeroKey(reflect.ValueOf(v).Elem()) != zero {
+			return ErrMixedPrimaryKey
+		}
+	}
+
+	columns := r.meta.columns
+	if zero {
+		columns = r.meta.columnsExcept(r.opts.primaryKey)
+	}
+
+	sess := r.session(ctx)
+	for start := 0; start < len(list); start += r.opts.chunkSize {
+		end := start + r.opts.chunkSize
+		if end > len(list) {
+			end = len(list)
+		}
+
+		stmt := sess.InsertInto(r.table).Columns(columns...)
+		for _, v := range list[start:end] {
+			rv := reflect.ValueOf(v).Elem()
+			r.meta.touch(rv, columnCtime, columnMtime)
+
+			values := make([]interface{}, 0, len(columns))
+			for _, c := range columns {
+				values = append(values, r.meta.value(rv, c).Interface())
+			}
+			stmt.Values(values...)
+		}
+
+		if _, err = sess.Exec(stmt); err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Update 只更新 old 与 cur 之间发生变化的列, 并刷新 mtime
+func (r *Repo[T]) Update(ctx context.Context, old, cur *T) (affected int64, err error) {
+	ov, cv := reflect.ValueOf(old).Elem(), reflect.ValueOf(cur).Elem()
+
+	changed := map[string]interface{}{}
+	for _, f := range r.meta.fields {
+		if f.column == r.opts.primaryKey || f.column == columnCtime || f.column == columnMtime {
+			continue
+		}
+
+		a, b := ov.FieldByIndex(f.index).Interface(), cv.FieldByIndex(f.index).Interface()
+		if !reflect.DeepEqual(a, b) {
+			changed[f.column] = b
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+
+	r.meta.touch(cv, columnMtime)
+	if r.meta.has(columnMtime) {
+		changed[columnMtime] = r.meta.value(cv, columnMtime).Interface()
+	}
+	return r.UpdateFields(ctx, r.meta.value(cv, r.opts.primaryKey).Interface(), changed)
+}
+
+// UpdateFields 按主键更新指定列, 未指定 mtime 时自动刷新
+func (r *Repo[T]) UpdateFields(ctx context.Context, id interface{}, fields map[string]interface{}) (affected int64, err error) {
+	if _, ok := fields[columnMtime]; !ok && r.meta.has(columnMtime) {
+		fields[columnMtime] = r.now(columnMtime)
+	}
+
+	sess := r.session(ctx)
+	stmt := sess.Update(r.table).SetMap(fields).Where(dbr.Eq(r.opts.primaryKey, id))
+	result, err := sess.Exec(stmt)
+	if err != nil {
+		return
+	}
+	return result.RowsAffected()
+}
+
+// Delete 存在 is_delete 列时软删除, 否则物理删除
+func (r *Repo[T]) Delete(ctx context.Context, id interface{}) (affected int64, err error) {
+	if r.opts.softDelete {
+		return r.UpdateFields(ctx, id, map[string]interface{}{columnIsDelete: 1})
+	}
+
+	sess := r.session(ctx)
+	result, err := sess.Exec(sess.DeleteFrom(r.table).Where(dbr.Eq(r.opts.primaryKey, id)))
+	if err != nil {
+		return
+	}
+	return result.RowsAffected()
+}
+
+// now 按列的 Go 类型生成当前时间
+func (r *Repo[T]) now(column string) interface{} {
+	fv := reflect.New(reflect.TypeOf((*T)(nil)).Elem()).Elem()
+	return timeValue(r.meta.value(fv, column), time.Now())
+}
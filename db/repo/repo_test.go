@@ -0,0 +1,75 @@
+package repo_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sujunbo/micro/db/dbtest"
+	"github.com/sujunbo/micro/db/repo"
+)
+
+type shop struct {
+	ShopID int64  `db:"shop_id"`
+	Name   string `db:"name"`
+}
+
+type item struct {
+	ID   int64  `db:"id"`
+	Name string `db:"name"`
+}
+
+func TestInsertNaturalKey(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+	if _, err := conn.Exec("CREATE TABLE shop_tab (shop_id INTEGER PRIMARY KEY, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	r := repo.New[shop](conn, "shop_tab", repo.WithPrimaryKey("shop_id"))
+	ctx := context.Background()
+	if err := r.Insert(ctx, &shop{ShopID: 42, Name: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.BatchInsert(ctx, []*shop{{ShopID: 7, Name: "b"}, {ShopID: 8, Name: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int64{42, 7, 8} {
+		if _, err := r.Get(ctx, id); err != nil {
+			t.Fatalf("get shop %v: %v", id, err)
+		}
+	}
+
+	if err := r.BatchInsert(ctx, []*shop{{ShopID: 9}, {Name: "zero"}}); !errors.Is(err, repo.ErrMixedPrimaryKey) {
+		t.Fatalf("expect ErrMixedPrimaryKey, got %v", err)
+	}
+}
+
+func TestInsertAutoIncrement(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+	if _, err := conn.Exec("CREATE TABLE item_tab (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+
+	// 非法的 chunk size 回退为默认值, 不会死循环
+	r := repo.New[item](conn, "item_tab", repo.WithChunkSize(0))
+	ctx := context.Background()
+	v := &item{Name: "a"}
+	if err := r.Insert(ctx, v); err != nil {
+		t.Fatal(err)
+	}
+	if v.ID == 0 {
+		t.Fatal("expect auto increment id written back")
+	}
+
+	if err := r.BatchInsert(ctx, []*item{{Name: "b"}, {Name: "c"}}); err != nil {
+		t.Fatal(err)
+	}
+	list, err := r.List(ctx, repo.Query{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Fatalf("expect 3 items, got %d", len(list))
+	}
+}
@@ -0,0 +1,124 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/gocraft/dbr"
+)
+
+var (
+	ErrQueryTimeout  = errors.New("db query timeout")
+	ErrQueryCanceled = errors.New("db query canceled")
+)
+
+// Session 绑定请求 ctx 的会话, ctx 取消(如 gin 请求断开)或超过 Options.QueryTimeout 时查询被中断
+type Session struct {
+	*dbr.Session
+	ctx   context.Context
+	cache *Cache
+	guard *guard
+}
+
+type execer interface {
+	ExecContext(ctx context.Context) (sql.Result, error)
+}
+
+// NewSessionContext 一般传入 gin handler 的 ctx
+func (c *Connection) NewSessionContext(ctx context.Context) *Session {
+	return &Session{
+		Session: c.NewSession(),
+		ctx:     ctx,
+		cache:   c.cache,
+		guard:   c.guard,
+	}
+}
+
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Session) Load(stmt *dbr.SelectStmt, value interface{}) (n int, err error) {
+	err = s.guard.do(func() error {
+		ctx, h := withSpanHolder(s.ctx)
+		n, err = stmt.LoadContext(ctx, value)
+		h.finish(int64(n))
+		return ctxError(err)
+	})
+	return
+}
+
+func (s *Session) LoadOne(stmt *dbr.SelectStmt, value interface{}) error {
+	return s.guard.do(func() error {
+		ctx, h := withSpanHolder(s.ctx)
+		err := stmt.LoadOneContext(ctx, value)
+		if err != nil {
+			h.finish(0)
+		} else {
+			h.finish(1)
+		}
+		return ctxError(err)
+	})
+}
+
+// Exec 执行 InsertStmt/UpdateStmt/DeleteStmt
+func (s *Session) Exec(stmt execer) (result sql.Result, err error) {
+	err = s.guard.do(func() error {
+		ctx, h := withSpanHolder(s.ctx)
+		result, err = stmt.ExecContext(ctx)
+		rows := int64(-1)
+		if err == nil {
+			rows, _ = result.RowsAffected()
+		}
+		h.finish(rows)
+		return ctxError(err)
+	})
+	return
+}
+
+// Begin 开启事务, 连接开启缓存时事务内写过的表在提交后再次失效
+func (s *Session) Begin() (*dbr.Tx, error) {
+	tx, err := s.Session.BeginTx(s.ctx, nil)
+	if err != nil {
+		return nil, ctxError(err)
+	}
+
+	if r, ok := tx.EventReceiver.(*sqlEventReceiver); ok && s.cache != nil {
+		tx.EventReceiver = &txEventReceiver{sqlEventReceiver: r, cache: s.cache, tables: map[string]struct{}{}}
+	}
+	return tx, nil
+}
+
+// ctxError 区分超时与调用方取消, 可用 errors.Is 匹配, 原始错误保留在错误链中
+func ctxError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, ErrQueryTimeout), errors.Is(err, ErrQueryCanceled):
+		return err
+	case errors.Is(err, context.DeadlineExceeded):
+		return &queryError{kind: ErrQueryTimeout, err: err}
+	case errors.Is(err, context.Canceled):
+		return &queryError{kind: ErrQueryCanceled, err: err}
+	}
+	return err
+}
+
+// queryError 同时匹配 ErrQueryTimeout/ErrQueryCanceled 与原始错误(context 错误、驱动错误)
+type queryError struct {
+	kind error
+	err  error
+}
+
+func (e *queryError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *queryError) Is(target error) bool {
+	return target == e.kind
+}
+
+func (e *queryError) Unwrap() error {
+	return e.err
+}
@@ -0,0 +1,91 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sujunbo/micro/db"
+)
+
+// slowQuery 在 sqlite 中需要数秒才能完成, 由 ctx 中断
+const slowQuery = "WITH RECURSIVE c(x) AS (SELECT 1 UNION ALL SELECT x + 1 FROM c WHERE x < 1000000000) SELECT count(*) FROM c"
+
+func TestQueryTimeout(t *testing.T) {
+	conn, err := db.New(&db.Options{
+		Name:         t.Name(),
+		Driver:       db.DriverSQLite3,
+		DataSource:   filepath.Join(t.TempDir(), "timeout.db"),
+		QueryTimeout: 50 * time.Millisecond,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	sess := conn.NewSessionContext(context.Background())
+	var n int64
+	err = sess.LoadOne(sess.SelectBySql(slowQuery), &n)
+	if !errors.Is(err, db.ErrQueryTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expect ErrQueryTimeout wrapping context.DeadlineExceeded, got %v", err)
+	}
+	if errors.Is(err, db.ErrQueryCanceled) {
+		t.Fatalf("timeout must not match ErrQueryCanceled: %v", err)
+	}
+
+	if got := metricValue(t, "db_query_timeouts_total", t.Name()); got != 1 {
+		t.Fatalf("expect db_query_timeouts_total 1, got %v", got)
+	}
+	if got := metricValue(t, "db_query_errors_total", t.Name()); got != 0 {
+		t.Fatalf("expect db_query_errors_total 0, got %v", got)
+	}
+}
+
+func TestQueryCanceled(t *testing.T) {
+	conn, err := db.New(&db.Options{
+		Name:       t.Name(),
+		Driver:     db.DriverSQLite3,
+		DataSource: filepath.Join(t.TempDir(), "cancel.db"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	sess := conn.NewSessionContext(ctx)
+	var n int64
+	err = sess.LoadOne(sess.SelectBySql(slowQuery), &n)
+	if !errors.Is(err, db.ErrQueryCanceled) || !errors.Is(err, context.Canceled) {
+		t.Fatalf("expect ErrQueryCanceled wrapping context.Canceled, got %v", err)
+	}
+	if errors.Is(err, db.ErrQueryTimeout) {
+		t.Fatalf("cancel must not match ErrQueryTimeout: %v", err)
+	}
+}
+
+// metricValue 汇总 db 标签为 dbname 的计数
+func metricValue(t *testing.T, name string, dbname string) (sum float64) {
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != name {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			for _, l := range m.GetLabel() {
+				if l.GetName() == "db" && l.GetValue() == dbname {
+					sum += m.GetCounter().GetValue()
+				}
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,331 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocraft/dbr"
+)
+
+var (
+	ErrShardNotFound = errors.New("shard table not registered")
+	ErrShardKey      = errors.New("shard key type not support")
+	// CreateShards 不支持当前方言, 如 sqlite3
+	ErrCreateShardNotSupported = errors.New("create shard not supported")
+)
+
+// ShardStrategy 根据分表键计算物理表后缀
+type ShardStrategy interface {
+	// Suffix returns the table suffix for key
+	Suffix(key interface{}) (string, error)
+	// Suffixes returns all table suffixes covered by [from, to]
+	Suffixes(from, to interface{}) ([]string, error)
+}
+
+// ShardTable 逻辑表, 如 push_data_tab -> push_data_tab_20200401
+type ShardTable struct {
+	Name     string
+	Strategy ShardStrategy
+}
+
+func (t *ShardTable) Table(key interface{}) (string, error) {
+	suffix, err := t.Strategy.Suffix(key)
+	if err != nil {
+		return "", err
+	}
+	return t.Name + "_" + suffix, nil
+}
+
+func (t *ShardTable) Tables(from, to interface{}) (tables []string, err error) {
+	suffixes, err := t.Strategy.Suffixes(from, to)
+	if err != nil {
+		return
+	}
+
+	for _, suffix := range suffixes {
+		tables = append(tables, t.Name+"_"+suffix)
+	}
+	return
+}
+
+var (
+	shardMutex  sync.RWMutex
+	shardTables = map[string]*ShardTable{}
+)
+
+func RegisterShard(name string, strategy ShardStrategy) *ShardTable {
+	shardMutex.Lock()
+	defer shardMutex.Unlock()
+
+	t := &ShardTable{Name: name, Strategy: strategy}
+	shardTables[name] = t
+	return t
+}
+
+func Shard(name string) (*ShardTable, error) {
+	shardMutex.RLock()
+	defer shardMutex.RUnlock()
+
+	t, ok := shardTables[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", ErrShardNotFound, name)
+	}
+	return t, nil
+}
+
+// ShardTableName 返回逻辑表 name 在 key 下的物理表名
+func ShardTableName(name string, key interface{}) (string, error) {
+	t, err := Shard(name)
+	if err != nil {
+		return "", err
+	}
+	return t.Table(key)
+}
+
+// logicalTable 将已注册分表的物理表名还原为逻辑表名, 如 push_data_tab_20200401 -> push_data_tab,
+// 用于指标等需要限制基数的场景; 未匹配时原样返回
+func logicalTable(table string) string {
+	shardMutex.RLock()
+	defer shardMutex.RUnlock()
+
+	logical := table
+	for name := range shardTables {
+		if len(name) >= len(logical) || !strings.HasPrefix(table, name+"_") {
+			continue
+		}
+		if isDigits(table[len(name)+1:]) {
+			logical = name
+		}
+	}
+	return logical
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+type dateStrategy struct {
+	layout string
+	next   func(time.Time) time.Time
+	trunc  func(time.Time) time.Time
+}
+
+// ByDay 按天分表 push_data_tab_20200401, key 为 time.Time 或 unix 秒
+func ByDay() ShardStrategy {
+	return &dateStrategy{
+		layout: "20060102",
+		next:   func(t time.Time) time.Time { return t.AddDate(0, 0, 1) },
+		trunc: func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+		},
+	}
+}
+
+// ByMonth 按月分表 push_data_tab_202004
+func ByMonth() ShardStrategy {
+	return &dateStrategy{
+		layout: "200601",
+		next:   func(t time.Time) time.Time { return t.AddDate(0, 1, 0) },
+		trunc: func(t time.Time) time.Time {
+			return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+		},
+	}
+}
+
+func (s *dateStrategy) Suffix(key interface{}) (string, error) {
+	t, err := shardTime(key)
+	if err != nil {
+		return "", err
+	}
+	return t.Format(s.layout), nil
+}
+
+func (s *dateStrategy) Suffixes(from, to interface{}) (suffixes []string, err error) {
+	begin, err := shardTime(from)
+	if err != nil {
+		return
+	}
+
+	end, err := shardTime(to)
+	if err != nil {
+		return
+	}
+
+	for t := s.trunc(begin); !t.After(end); t = s.next(t) {
+		suffixes = append(suffixes, t.Format(s.layout))
+	}
+	return
+}
+
+func shardTime(key interface{}) (time.Time, error) {
+	switch k := key.(type) {
+	case time.Time:
+		return k, nil
+	case *time.Time:
+		if k == nil {
+			return time.Time{}, fmt.Errorf("%w: nil %T", ErrShardKey, key)
+		}
+		return *k, nil
+	}
+
+	n, err := shardInt(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(int64(n), 0), nil
+}
+
+type modStrategy struct {
+	n uint64
+}
+
+// ByMod 按整数键取模分表 user_tab_0 ~ user_tab_{n-1}, n <= 0 时 panic; 负数键返回 ErrShardKey
+func ByMod(n int) ShardStrategy {
+	if n <= 0 {
+		panic(fmt.Sprintf("db: ByMod shard count must be positive, got %d", n))
+	}
+	return &modStrategy{n: uint64(n)}
+}
+
+func (s *modStrategy) Suffix(key interface{}) (string, error) {
+	k, err := shardInt(key)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d", k%s.n), nil
+}
+
+func (s *modStrategy) Suffixes(from, to interface{}) ([]string, error) {
+	return allSuffixes(s.n), nil
+}
+
+type hashStrategy struct {
+	n uint64
+}
+
+// ByHash 按键的 crc32 取模分表, 适用于字符串键, n <= 0 时 panic
+func ByHash(n int) ShardStrategy {
+	if n <= 0 {
+		panic(fmt.Sprintf("db: ByHash shard count must be positive, got %d", n))
+	}
+	return &hashStrategy{n: uint64(n)}
+}
+
+func (s *hashStrategy) Suffix(key interface{}) (string, error) {
+	var buf []byte
+	switch k := key.(type) {
+	case string:
+		buf = []byte(k)
+	case []byte:
+		buf = k
+	default:
+		buf = []byte(fmt.Sprint(key))
+	}
+	return fmt.Sprintf("%d", uint64(crc32.ChecksumIEEE(buf))%s.n), nil
+}
+
+func (s *hashStrategy) Suffixes(from, to interface{}) ([]string, error) {
+	return allSuffixes(s.n), nil
+}
+
+func allSuffixes(n uint64) (suffixes []string) {
+	for i := uint64(0); i < n; i++ {
+		suffixes = append(suffixes, fmt.Sprintf("%d", i))
+	}
+	return
+}
+
+func shardInt(key interface{}) (uint64, error) {
+	v := reflect.Indirect(reflect.ValueOf(key))
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() < 0 {
+			return 0, fmt.Errorf("%w: negative key %d", ErrShardKey, v.Int())
+		}
+		return uint64(v.Int()), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return v.Uint(), nil
+	}
+	return 0, fmt.Errorf("%w: %T", ErrShardKey, key)
+}
+
+// UnionSelect 将多个分表的查询拼接为 SELECT * FROM (... UNION ALL ...) AS t
+// build 对每个分表的 SelectStmt 追加 where/order 等条件
+func UnionSelect(sess dbr.SessionRunner, tables []string, build func(*dbr.SelectStmt) *dbr.SelectStmt, column ...string) *dbr.SelectStmt {
+	columns := make([]interface{}, len(column))
+	for i, c := range column {
+		columns[i] = c
+	}
+
+	builders := make([]dbr.Builder, 0, len(tables))
+	for _, table := range tables {
+		stmt := dbr.Select(columns...).From(table)
+		if build != nil {
+			stmt = build(stmt)
+		}
+		builders = append(builders, stmt)
+	}
+	return sess.Select("*").From(dbr.UnionAll(builders...).As("t"))
+}
+
+// LoadShards 依次查询各分表并将结果追加到 value(slice 指针)
+func LoadShards(ctx context.Context, sess dbr.SessionRunner, tables []string, build func(*dbr.SelectStmt) *dbr.SelectStmt, value interface{}, column ...string) (count int, err error) {
+	for _, table := range tables {
+		stmt := sess.Select(column...).From(table)
+		if build != nil {
+			stmt = build(stmt)
+		}
+
+		n, err := stmt.LoadContext(ctx, value)
+		if err != nil {
+			return count, err
+		}
+		count += n
+	}
+	return
+}
+
+// CreateShards 按模板表预建 [from, to] 范围内的分表, 支持 mysql 与 postgres, 其他方言返回 ErrCreateShardNotSupported
+func (c *Connection) CreateShards(ctx context.Context, name string, template string, from, to interface{}) (err error) {
+	t, err := Shard(name)
+	if err != nil {
+		return
+	}
+
+	tables, err := t.Tables(from, to)
+	if err != nil {
+		return
+	}
+
+	var format string
+	switch dialectDriver(c.Dialect) {
+	case DriverMySQL:
+		format = "CREATE TABLE IF NOT EXISTS %s LIKE %s"
+	case DriverPostgres:
+		format = "CREATE TABLE IF NOT EXISTS %s (LIKE %s INCLUDING ALL)"
+	default:
+		return fmt.Errorf("%w: dialect %T", ErrCreateShardNotSupported, c.Dialect)
+	}
+
+	for _, table := range tables {
+		query := fmt.Sprintf(format, c.Dialect.QuoteIdent(table), c.Dialect.QuoteIdent(template))
+		if _, err = c.ExecContext(ctx, query); err != nil {
+			return fmt.Errorf("create shard %v err:%w", table, err)
+		}
+	}
+	return
+}
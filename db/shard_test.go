@@ -0,0 +1,158 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/gocraft/dbr"
+)
+
+func TestModSuffix(t *testing.T) {
+	s := ByMod(4)
+	tests := []struct {
+		key    interface{}
+		suffix string
+		err    error
+	}{
+		{int64(7), "3", nil},
+		{uint8(4), "0", nil},
+		{-1, "", ErrShardKey},
+		{"7", "", ErrShardKey},
+	}
+	for _, tt := range tests {
+		suffix, err := s.Suffix(tt.key)
+		if !errors.Is(err, tt.err) || suffix != tt.suffix {
+			t.Errorf("Suffix(%v) = %q, %v, want %q, %v", tt.key, suffix, err, tt.suffix, tt.err)
+		}
+	}
+}
+
+func TestShardCount(t *testing.T) {
+	for name, fn := range map[string]func(int) ShardStrategy{"ByMod": ByMod, "ByHash": ByHash} {
+		for _, n := range []int{0, -1} {
+			func() {
+				defer func() {
+					if recover() == nil {
+						t.Errorf("%s(%d) expect panic", name, n)
+					}
+				}()
+				fn(n)
+			}()
+		}
+	}
+}
+
+func TestDateSuffixes(t *testing.T) {
+	date := func(y int, m time.Month, d, h int) time.Time {
+		return time.Date(y, m, d, h, 0, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		name     string
+		strategy ShardStrategy
+		from, to interface{}
+		want     []string
+	}{
+		{"day", ByDay(), date(2020, 3, 30, 10), date(2020, 4, 2, 1), []string{"20200330", "20200331", "20200401", "20200402"}},
+		{"day pointer", ByDay(), &[]time.Time{date(2020, 4, 1, 23)}[0], date(2020, 4, 1, 0), []string{"20200401"}},
+		{"month across year", ByMonth(), date(2019, 11, 30, 0), date(2020, 2, 1, 0), []string{"201911", "201912", "202001", "202002"}},
+		{"month from month end", ByMonth(), date(2020, 1, 31, 0), date(2020, 3, 1, 0), []string{"202001", "202002", "202003"}},
+		{"reversed", ByDay(), date(2020, 4, 2, 0), date(2020, 4, 1, 0), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.strategy.Suffixes(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expect %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDateSuffixKey(t *testing.T) {
+	var nilTime *time.Time
+	for _, key := range []interface{}{nilTime, "20200401", nil} {
+		if _, err := ByDay().Suffix(key); !errors.Is(err, ErrShardKey) {
+			t.Errorf("Suffix(%#v) expect ErrShardKey, got %v", key, err)
+		}
+	}
+
+	unix := int64(1585699200)
+	got, err := ByDay().Suffix(unix)
+	if want := time.Unix(unix, 0).Format("20060102"); err != nil || got != want {
+		t.Fatalf("expect %v, got %v %v", want, got, err)
+	}
+}
+
+func TestUnionSelect(t *testing.T) {
+	stmt := UnionSelect((&dbr.Connection{}).NewSession(nil), []string{"push_data_tab_20200401", "push_data_tab_20200402"},
+		func(s *dbr.SelectStmt) *dbr.SelectStmt { return s.Where("shop_id = ?", 1) }, "id", "shop_id")
+
+	buf := dbr.NewBuffer()
+	if err := stmt.Build(&mysql{}, buf); err != nil {
+		t.Fatal(err)
+	}
+	query, err := dbr.InterpolateForDialect(buf.String(), buf.Value(), &mysql{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "SELECT * FROM (SELECT id, shop_id FROM push_data_tab_20200401 WHERE (shop_id = 1) UNION ALL " +
+		"SELECT id, shop_id FROM push_data_tab_20200402 WHERE (shop_id = 1)) AS `t`"
+	if query != want {
+		t.Fatalf("got  %s\nwant %s", query, want)
+	}
+}
+
+func TestCreateShards(t *testing.T) {
+	RegisterShard("create_push_data_tab", ByDay())
+	from := time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, 1)
+
+	tests := []struct {
+		driver  string
+		queries []string
+		err     error
+	}{
+		{DriverMySQL, []string{
+			"CREATE TABLE IF NOT EXISTS `create_push_data_tab_20200401` LIKE `create_push_data_tab`",
+			"CREATE TABLE IF NOT EXISTS `create_push_data_tab_20200402` LIKE `create_push_data_tab`",
+		}, nil},
+		{DriverPostgres, []string{
+			`CREATE TABLE IF NOT EXISTS "create_push_data_tab_20200401" (LIKE "create_push_data_tab" INCLUDING ALL)`,
+			`CREATE TABLE IF NOT EXISTS "create_push_data_tab_20200402" (LIKE "create_push_data_tab" INCLUDING ALL)`,
+		}, nil},
+		{DriverSQLite3, nil, ErrCreateShardNotSupported},
+	}
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			sqlDB, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatal(err)
+			}
+			conn, err := Wrap(sqlDB, &Options{Name: "create_shards_" + tt.driver, Driver: tt.driver, MaxIdleConns: 1})
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+
+			for _, q := range tt.queries {
+				mock.ExpectExec(q).WillReturnResult(sqlmock.NewResult(0, 0))
+			}
+
+			err = conn.CreateShards(context.Background(), "create_push_data_tab", "create_push_data_tab", from, to)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expect %v, got %v", tt.err, err)
+			}
+			if err = mock.ExpectationsWereMet(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package db
+
+import (
+	"strings"
+	"unicode"
+)
+
+const (
+	OpSelect = "select"
+	OpInsert = "insert"
+	OpUpdate = "update"
+	OpDelete = "delete"
+	OpOther  = "other"
+)
+
+// sqlTokens 将 sql 切分为标识符/关键字/符号, 去掉注释和字符串字面量,
+// 反引号和双引号包裹的标识符会去掉引号
+func sqlTokens(query string) (tokens []string) {
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			end := strings.Index(query[i+2:], "*/")
+			if end == -1 {
+				return
+			}
+			i += end + 4
+		case c == '-' && i+1 < len(query) && query[i+1] == '-', c == '#':
+			end := strings.IndexByte(query[i:], '\n')
+			if end == -1 {
+				return
+			}
+			i += end + 1
+		case c == '\'':
+			i = skipQuoted(query, i, '\'')
+			tokens = append(tokens, "?")
+		case c == '`' || c == '"':
+			end := skipQuoted(query, i, c)
+			tokens = appendIdent(tokens, strings.TrimSuffix(query[i+1:end], string(c)))
+			i = end
+		case isIdentByte(c):
+			start := i
+			for i < len(query) && (isIdentByte(query[i]) || query[i] == '.') {
+				i++
+			}
+			tokens = appendIdent(tokens, query[start:i])
+		case unicode.IsSpace(rune(c)):
+			i++
+		default:
+			tokens = append(tokens, string(c))
+			i++
+		}
+	}
+	return
+}
+
+// appendIdent 合并 `db`.`table` 形式的限定名
+func appendIdent(tokens []string, ident string) []string {
+	if n := len(tokens); n >= 2 && tokens[n-1] == "." {
+		tokens[n-2] += "." + ident
+		return tokens[:n-1]
+	}
+	return append(tokens, ident)
+}
+
+func skipQuoted(query string, i int, quote byte) int {
+	for j := i + 1; j < len(query); j++ {
+		switch query[j] {
+		case '\\':
+			j++
+		case quote:
+			// '' 转义
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+	}
+	return len(query)
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+// parseQuery 返回 sql 的操作类型与主表名, 如
+// SELECT * FROM `push_data_tab_20200401` AS p JOIN ... -> select, push_data_tab_20200401
+// update IGNORE `db`.`crm_shop_attach_tab` SET ... -> update, crm_shop_attach_tab
+func parseQuery(query string) (op string, table string) {
+	tokens := sqlTokens(query)
+	// (SELECT ...) UNION (SELECT ...)
+	for len(tokens) > 0 && tokens[0] == "(" {
+		tokens = tokens[1:]
+	}
+	if len(tokens) == 0 {
+		return OpOther, ""
+	}
+
+	switch strings.ToLower(tokens[0]) {
+	case "select", "with":
+		op = OpSelect
+		table = tableAfter(tokens, "from")
+	case "insert", "replace":
+		op = OpInsert
+		table = tableAfter(tokens, "into")
+	case "update":
+		op = OpUpdate
+		table = tableAfter(tokens, "update")
+	case "delete":
+		op = OpDelete
+		table = tableAfter(tokens, "from")
+	default:
+		op = OpOther
+	}
+	return
+}
+
+// tableAfter 返回第一个 keyword 之后的表名, 跳过修饰词与子查询
+func tableAfter(tokens []string, keyword string) string {
+	depth := 0
+	for i, t := range tokens {
+		switch t {
+		case "(":
+			depth++
+			continue
+		case ")":
+			depth--
+			continue
+		}
+
+		if depth != 0 || !strings.EqualFold(t, keyword) {
+			continue
+		}
+
+		for _, next := range tokens[i+1:] {
+			switch strings.ToLower(next) {
+			case "low_priority", "ignore", "quick", "delayed", "high_priority", "only":
+				continue
+			case "(":
+				// FROM (subquery) 取子查询的表
+				return tableAfter(tokens[i+2:], "from")
+			}
+			return stripSchema(next)
+		}
+		return ""
+	}
+	return ""
+}
+
+func stripSchema(name string) string {
+	if idx := strings.LastIndex(name, "."); idx != -1 {
+		return name[idx+1:]
+	}
+	return name
+}
+
+// normalizeQuery 将字符串与数字字面量替换为 ?, 用于 trace 中的 db.statement 与审计日志;
+// mysql 默认将双引号视为字符串, 其他方言中双引号为标识符
+func normalizeQuery(driver string, query string) string {
+	var buf strings.Builder
+	buf.Grow(len(query))
+	for i := 0; i < len(query); {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' && driver == DriverMySQL:
+			i = skipQuoted(query, i, c)
+			buf.WriteByte('?')
+		case c == '`' || c == '"':
+			end := skipQuoted(query, i, c)
+			buf.WriteString(query[i:end])
+			i = end
+		case c >= '0' && c <= '9' && (i == 0 || !isIdentByte(query[i-1])):
+			for i < len(query) && (isIdentByte(query[i]) || query[i] == '.') {
+				i++
+			}
+			buf.WriteByte('?')
+		case isIdentByte(c):
+			start := i
+			for i < len(query) && isIdentByte(query[i]) {
+				i++
+			}
+			buf.WriteString(query[start:i])
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,81 @@
+package db
+
+import "testing"
+
+func TestNormalizeQueryRedact(t *testing.T) {
+	tests := []struct {
+		driver string
+		query  string
+		want   string
+	}{
+		{DriverMySQL, `UPDATE t SET s = 'secret' WHERE id = 1`, `UPDATE t SET s = ? WHERE id = ?`},
+		{DriverMySQL, `UPDATE t SET s = "secret" WHERE id = 1`, `UPDATE t SET s = ? WHERE id = ?`},
+		{DriverMySQL, `INSERT INTO t (a,b) VALUES ("it""s", 'it''s')`, `INSERT INTO t (a,b) VALUES (?, ?)`},
+		{DriverMySQL, `INSERT INTO t (a) VALUES ('a\'b "c"')`, `INSERT INTO t (a) VALUES (?)`},
+		{DriverMySQL, `INSERT INTO t (a) VALUES ("a\"b 'c'")`, `INSERT INTO t (a) VALUES (?)`},
+		{DriverMySQL, "UPDATE `t` SET `s` = \"x\"", "UPDATE `t` SET `s` = ?"},
+		{DriverMySQL, `DELETE FROM t WHERE s IN ("a", "b") AND n > -1.5`, `DELETE FROM t WHERE s IN (?, ?) AND n > -?`},
+		{DriverPostgres, `UPDATE "t" SET "s" = 'secret'`, `UPDATE "t" SET "s" = ?`},
+		{DriverSQLite3, `INSERT INTO "t" ("a") VALUES ('x')`, `INSERT INTO "t" ("a") VALUES (?)`},
+	}
+	for _, tt := range tests {
+		if got := normalizeQuery(tt.driver, tt.query); got != tt.want {
+			t.Errorf("normalizeQuery(%v, %q) = %q, want %q", tt.driver, tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		op    string
+		table string
+	}{
+		{"SELECT * FROM `push_data_tab_20200401` WHERE id = 1", OpSelect, "push_data_tab_20200401"},
+		{"select a.id from orders a left join users u on u.id = a.uid", OpSelect, "orders"},
+		{"SELECT o.id FROM `orders` o INNER JOIN `users` u ON u.id = o.uid WHERE u.name = 'from x'", OpSelect, "orders"},
+		{"select * from `shop`.`orders` where 1", OpSelect, "orders"},
+		{"SELECT * FROM shop.orders", OpSelect, "orders"},
+		{"SELECT count(*) FROM (SELECT * FROM `t1` UNION ALL SELECT * FROM t2) AS t", OpSelect, "t1"},
+		{"SELECT id FROM a WHERE id IN (SELECT aid FROM b)", OpSelect, "a"},
+		{"(SELECT id FROM a) UNION (SELECT id FROM b)", OpSelect, "a"},
+		{"select 'from z', x from `q` where s = 'it''s from y'", OpSelect, "q"},
+		{"/* hint */ SELECT * FROM t1 -- trailing from t2\n", OpSelect, "t1"},
+		{"# comment from t0\nselect * from t1", OpSelect, "t1"},
+		{"WITH c AS (SELECT 1) SELECT * FROM c", OpSelect, "c"},
+		{"INSERT INTO `crm_shop_attach_tab` (`id`,`shop_id`) VALUES (0,439510)", OpInsert, "crm_shop_attach_tab"},
+		{"insert ignore into db.t (a) values ('x')", OpInsert, "t"},
+		{"REPLACE INTO t (a) VALUES (1)", OpInsert, "t"},
+		{"UPDATE `push_data_tab_20200401` SET `push_flag` = 1 WHERE (`user_id` IN (442547))", OpUpdate, "push_data_tab_20200401"},
+		{"update low_priority `db`.tab set a = 1", OpUpdate, "tab"},
+		{"/* hint */ delete low_priority from db.x where id = 'it''s from y'", OpDelete, "x"},
+		{"DELETE FROM \"t\" WHERE id = 1", OpDelete, "t"},
+		{"SET NAMES utf8", OpOther, ""},
+		{"", OpOther, ""},
+	}
+	for _, tt := range tests {
+		op, table := parseQuery(tt.query)
+		if op != tt.op || table != tt.table {
+			t.Errorf("parseQuery(%q) = %v, %v, want %v, %v", tt.query, op, table, tt.op, tt.table)
+		}
+	}
+}
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM `t1` WHERE id = 1", "SELECT * FROM `t1` WHERE id = ?"},
+		{"select * from t where a in (1, 2.5, 3)", "select * from t where a in (?, ?, ?)"},
+		{"SELECT * FROM `push_data_tab_20200401` WHERE biz_id = 'dc5d'", "SELECT * FROM `push_data_tab_20200401` WHERE biz_id = ?"},
+		{"SELECT * FROM t WHERE s = 'it''s' AND n = 'a\\'b'", "SELECT * FROM t WHERE s = ? AND n = ?"},
+		{"SELECT col1, t2.col2 FROM db.t2 LIMIT 10 OFFSET 20", "SELECT col1, t2.col2 FROM db.t2 LIMIT ? OFFSET ?"},
+		{"SELECT * FROM t WHERE id IN (SELECT id FROM u WHERE x = 'y')", "SELECT * FROM t WHERE id IN (SELECT id FROM u WHERE x = ?)"},
+	}
+	for _, tt := range tests {
+		if got := normalizeQuery(DriverMySQL, tt.query); got != tt.want {
+			t.Errorf("normalizeQuery(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+var (
+	connMutex   sync.RWMutex
+	connections = map[*Connection]struct{}{}
+)
+
+type ConnectionStats struct {
+	Name  string
+	Stats sql.DBStats
+}
+
+func register(c *Connection) {
+	connMutex.Lock()
+	connections[c] = struct{}{}
+	connMutex.Unlock()
+
+	c.collector = collectors.NewDBStatsCollector(c.DB, c.name)
+	if err := prometheus.Register(c.collector); err != nil {
+		are := prometheus.AlreadyRegisteredError{}
+		if !errors.As(err, &are) {
+			panic(err)
+		}
+		c.collector = nil
+	}
+}
+
+func unregister(c *Connection) {
+	connMutex.Lock()
+	delete(connections, c)
+	connMutex.Unlock()
+
+	if c.collector != nil {
+		prometheus.Unregister(c.collector)
+	}
+}
+
+// Stats 返回所有已打开连接池的状态
+func Stats() (stats []ConnectionStats) {
+	connMutex.RLock()
+	defer connMutex.RUnlock()
+
+	for c := range connections {
+		stats = append(stats, ConnectionStats{Name: c.name, Stats: c.DB.Stats()})
+	}
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return
+}
+
+// StatsHandler 管理端接口, 以 json 输出所有连接池状态
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(Stats())
+	})
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/sujunbo/micro/db"
+
+type spanHolderKey struct{}
+
+// spanHolder 由 Session 放入 ctx, 使 span 在拿到影响行数之后再结束
+type spanHolder struct {
+	mutex sync.Mutex
+	span  trace.Span
+	audit *auditEntry
+}
+
+func withSpanHolder(ctx context.Context) (context.Context, *spanHolder) {
+	h := &spanHolder{}
+	return context.WithValue(ctx, spanHolderKey{}, h), h
+}
+
+func (h *spanHolder) finish(rows int64) {
+	h.mutex.Lock()
+	defer h.mutex.Unlock()
+
+	if h.audit != nil {
+		h.audit.finish(rows)
+		h.audit = nil
+	}
+
+	if h.span == nil {
+		return
+	}
+	if rows >= 0 {
+		h.span.SetAttributes(attribute.Int64("db.rows_affected", rows))
+	}
+	h.span.End()
+	h.span = nil
+}
+
+// SpanStart 实现 dbr.TracingEventReceiver, 父 span 取自 ctx(gin 请求的 ctx)
+func (s *sqlEventReceiver) SpanStart(ctx context.Context, eventName, query string) context.Context {
+	op, table := queryLabels(eventName, query)
+	ctx, _ = s.tracer().Start(ctx, op+" "+table,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", s.system),
+			attribute.String("db.name", s.dbname),
+			attribute.String("db.operation", op),
+			attribute.String("db.sql.table", table),
+			attribute.String("db.statement", normalizeQuery(s.system, query)),
+		))
+
+	if s.audit != nil && (op == OpInsert || op == OpUpdate || op == OpDelete) {
+		if e := s.audit.begin(ctx, s.dbname, s.system, op, table, query); e != nil {
+			ctx = context.WithValue(ctx, auditKey{}, e)
+		}
+	}
+	return ctx
+}
+
+func (s *sqlEventReceiver) SpanError(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	if e := auditFromContext(ctx); e != nil {
+		e.fail(err)
+	}
+}
+
+func (s *sqlEventReceiver) SpanFinish(ctx context.Context) {
+	span := trace.SpanFromContext(ctx)
+	e := auditFromContext(ctx)
+	if h, ok := ctx.Value(spanHolderKey{}).(*spanHolder); ok {
+		h.mutex.Lock()
+		h.span = span
+		h.audit = e
+		h.mutex.Unlock()
+		return
+	}
+
+	if e != nil {
+		e.finish(-1)
+	}
+	span.End()
+}
+
+func (s *sqlEventReceiver) tracer() trace.Tracer {
+	tp := s.tracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	return tp.Tracer(tracerName)
+}
@@ -0,0 +1,67 @@
+package db_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/db/dbtest"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+func TestTraceSpan(t *testing.T) {
+	tp, exporter := dbtest.NewTracerProvider()
+	conn, err := db.New(&db.Options{
+		Name:           t.Name(),
+		Driver:         db.DriverSQLite3,
+		DataSource:     filepath.Join(t.TempDir(), "trace.db"),
+		TracerProvider: tp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	if _, err = conn.Exec("CREATE TABLE shop_tab (id INTEGER, name TEXT)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = conn.Exec("INSERT INTO shop_tab VALUES (1, 'a'), (2, 'b')"); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "request")
+	sess := conn.NewSessionContext(ctx)
+	if _, err = sess.Exec(sess.Update("shop_tab").Set("name", "secret").Where("id > ?", 0)); err != nil {
+		t.Fatal(err)
+	}
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 2 || spans[0].Name != "update shop_tab" {
+		t.Fatalf("expect db span then parent, got %v", spans)
+	}
+
+	span := spans[0]
+	if span.Parent.SpanID() != parent.SpanContext().SpanID() {
+		t.Fatalf("expect parent %v, got %v", parent.SpanContext().SpanID(), span.Parent.SpanID())
+	}
+
+	want := map[attribute.Key]attribute.Value{
+		"db.system":        attribute.StringValue(db.DriverSQLite3),
+		"db.name":          attribute.StringValue(t.Name()),
+		"db.operation":     attribute.StringValue(db.OpUpdate),
+		"db.sql.table":     attribute.StringValue("shop_tab"),
+		"db.statement":     attribute.StringValue(`UPDATE "shop_tab" SET "name" = ? WHERE (id > ?)`),
+		"db.rows_affected": attribute.Int64Value(2),
+	}
+	got := map[attribute.Key]attribute.Value{}
+	for _, kv := range span.Attributes {
+		got[kv.Key] = kv.Value
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%v: expect %v, got %v", k, v.Emit(), got[k].Emit())
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/gocraft/dbr"
+)
+
+var (
+	ErrConcurrentModification = errors.New("concurrent modification")
+	ErrUpsertNotSupported     = errors.New("upsert not supported")
+)
+
+// UpdateVersion 乐观锁更新: SET version = version + 1 WHERE version = ?,
+// 没有行被更新时返回 ErrConcurrentModification
+func (s *Session) UpdateVersion(stmt *dbr.UpdateStmt, column string, version int64) error {
+	stmt.Set(column, dbr.Expr("? + 1", dbr.I(column))).Where(dbr.Eq(column, version))
+
+	result, err := s.Exec(stmt)
+	if err != nil {
+		return err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%w table:%v %v:%v", ErrConcurrentModification, stmt.Table, column, version)
+	}
+	return nil
+}
+
+// UpsertStmt 在 InsertStmt 基础上追加冲突更新子句:
+// mysql 为 ON DUPLICATE KEY UPDATE, postgres/sqlite3 为 ON CONFLICT (...) DO UPDATE
+//
+//	u := sess.UpsertInto("crm_shop_attach_tab")
+//	u.Columns("shop_id", "buyer_num", "mtime").Record(v)
+//	_, err := sess.Exec(u.OnConflict("shop_id").Update("buyer_num", "mtime"))
+type UpsertStmt struct {
+	*dbr.InsertStmt
+	ConflictColumn []string
+	UpdateColumn   []string
+}
+
+func NewUpsert(insert *dbr.InsertStmt) *UpsertStmt {
+	return &UpsertStmt{InsertStmt: insert}
+}
+
+func (s *Session) UpsertInto(table string) *UpsertStmt {
+	return NewUpsert(s.InsertInto(table))
+}
+
+// OnConflict 冲突判定的唯一键列, postgres/sqlite3 必填, mysql 忽略
+func (b *UpsertStmt) OnConflict(column ...string) *UpsertStmt {
+	b.ConflictColumn = column
+	return b
+}
+
+// Update 冲突时更新的列, 默认为除冲突列外的所有插入列
+func (b *UpsertStmt) Update(column ...string) *UpsertStmt {
+	b.UpdateColumn = column
+	return b
+}
+
+func (b *UpsertStmt) Build(d dbr.Dialect, buf dbr.Buffer) error {
+	if len(b.ReturnColumn) > 0 {
+		return fmt.Errorf("%w: returning", ErrUpsertNotSupported)
+	}
+
+	// 先确定方言再写入 buf, 不支持的方言不产生任何输出
+	driver := dialectDriver(d)
+	if driver == "" {
+		return fmt.Errorf("%w: dialect %T", ErrUpsertNotSupported, d)
+	}
+	if driver != DriverMySQL && len(b.ConflictColumn) == 0 {
+		return fmt.Errorf("%w: conflict columns required", ErrUpsertNotSupported)
+	}
+
+	if err := b.InsertStmt.Build(d, buf); err != nil {
+		return err
+	}
+
+	columns := b.updateColumns()
+	if driver == DriverMySQL {
+		if len(columns) == 0 {
+			columns = b.Column[:1]
+		}
+		buf.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, col := range columns {
+			if i > 0 {
+				buf.WriteString(", ")
+			}
+			buf.WriteString(d.QuoteIdent(col) + " = VALUES(" + d.QuoteIdent(col) + ")")
+		}
+		return nil
+	}
+
+	buf.WriteString(" ON CONFLICT (")
+	for i, col := range b.ConflictColumn {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString(d.QuoteIdent(col))
+	}
+	buf.WriteString(")")
+
+	if len(columns) == 0 {
+		buf.WriteString(" DO NOTHING")
+		return nil
+	}
+
+	buf.WriteString(" DO UPDATE SET ")
+	for i, col := range columns {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(d.QuoteIdent(col) + " = EXCLUDED." + d.QuoteIdent(col))
+	}
+	return nil
+}
+
+func (b *UpsertStmt) updateColumns() (columns []string) {
+	if len(b.UpdateColumn) > 0 {
+		return b.UpdateColumn
+	}
+
+	conflict := map[string]bool{}
+	for _, col := range b.ConflictColumn {
+		conflict[col] = true
+	}
+	for _, col := range b.Column {
+		if !conflict[col] {
+			columns = append(columns, col)
+		}
+	}
+	return
+}
+
+// ExecContext 经由 InsertStmt 的 runner 执行, 保留事件、指标与 trace
+func (b *UpsertStmt) ExecContext(ctx context.Context) (sql.Result, error) {
+	buf := dbr.NewBuffer()
+	if err := b.Build(b.Dialect, buf); err != nil {
+		return nil, err
+	}
+
+	raw := dbr.InsertBySql(buf.String(), buf.Value()...)
+	raw.Runner = b.Runner
+	raw.EventReceiver = b.EventReceiver
+	raw.Dialect = b.Dialect
+	return raw.ExecContext(ctx)
+}
+
+func (b *UpsertStmt) Exec() (sql.Result, error) {
+	return b.ExecContext(context.Background())
+}
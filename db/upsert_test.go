@@ -0,0 +1,162 @@
+package db_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/gocraft/dbr"
+	"github.com/gocraft/dbr/dialect"
+	"github.com/sujunbo/micro/db"
+	"github.com/sujunbo/micro/db/dbtest"
+)
+
+func TestUpsertBuild(t *testing.T) {
+	session := func(driver string) *db.Session {
+		conn, _ := dbtest.NewMock(t, driver, nil)
+		return conn.NewSessionContext(context.Background())
+	}
+	upsert := func(u *db.UpsertStmt) *db.UpsertStmt {
+		u.Columns("shop_id", "buyer_num", "mtime").Values(1, 2, 3)
+		return u
+	}
+
+	tests := []struct {
+		name    string
+		dialect dbr.Dialect
+		stmt    *db.UpsertStmt
+		want    string
+		err     error
+	}{
+		{
+			name:    "mysql",
+			dialect: session(db.DriverMySQL).Dialect,
+			stmt:    upsert(session(db.DriverMySQL).UpsertInto("t")).OnConflict("shop_id"),
+			want:    "INSERT INTO `t` (`shop_id`,`buyer_num`,`mtime`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `buyer_num` = VALUES(`buyer_num`), `mtime` = VALUES(`mtime`)",
+		},
+		{
+			name:    "dbr mysql",
+			dialect: dialect.MySQL,
+			stmt:    upsert(db.NewUpsert(dbr.InsertInto("t"))).Update("mtime"),
+			want:    "INSERT INTO `t` (`shop_id`,`buyer_num`,`mtime`) VALUES (?,?,?) ON DUPLICATE KEY UPDATE `mtime` = VALUES(`mtime`)",
+		},
+		{
+			name:    "postgres",
+			dialect: session(db.DriverPostgres).Dialect,
+			stmt:    upsert(session(db.DriverPostgres).UpsertInto("t")).OnConflict("shop_id"),
+			want:    `INSERT INTO "t" ("shop_id","buyer_num","mtime") VALUES (?,?,?) ON CONFLICT ("shop_id") DO UPDATE SET "buyer_num" = EXCLUDED."buyer_num", "mtime" = EXCLUDED."mtime"`,
+		},
+		{
+			name:    "sqlite3",
+			dialect: session(db.DriverSQLite3).Dialect,
+			stmt:    upsert(session(db.DriverSQLite3).UpsertInto("t")).OnConflict("shop_id").Update("mtime"),
+			want:    `INSERT INTO "t" ("shop_id","buyer_num","mtime") VALUES (?,?,?) ON CONFLICT ("shop_id") DO UPDATE SET "mtime" = EXCLUDED."mtime"`,
+		},
+		{
+			name:    "only conflict columns",
+			dialect: dialect.PostgreSQL,
+			stmt:    db.NewUpsert(dbr.InsertInto("t").Columns("shop_id").Values(1)).OnConflict("shop_id"),
+			want:    `INSERT INTO "t" ("shop_id") VALUES (?) ON CONFLICT ("shop_id") DO NOTHING`,
+		},
+		{
+			name:    "mysql only conflict columns",
+			dialect: dialect.MySQL,
+			stmt:    db.NewUpsert(dbr.InsertInto("t").Columns("shop_id").Values(1)).OnConflict("shop_id"),
+			want:    "INSERT INTO `t` (`shop_id`) VALUES (?) ON DUPLICATE KEY UPDATE `shop_id` = VALUES(`shop_id`)",
+		},
+		{
+			name:    "postgres without conflict columns",
+			dialect: dialect.PostgreSQL,
+			stmt:    upsert(db.NewUpsert(dbr.InsertInto("t"))),
+			err:     db.ErrUpsertNotSupported,
+		},
+		{
+			name:    "returning",
+			dialect: dialect.PostgreSQL,
+			stmt:    upsert(db.NewUpsert(dbr.InsertInto("t").Returning("id"))).OnConflict("shop_id"),
+			err:     db.ErrUpsertNotSupported,
+		},
+		{
+			name:    "unknown dialect",
+			dialect: unknownDialect{dialect.MySQL},
+			stmt:    upsert(db.NewUpsert(dbr.InsertInto("t"))).OnConflict("shop_id"),
+			err:     db.ErrUpsertNotSupported,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := dbr.NewBuffer()
+			err := tt.stmt.Build(tt.dialect, buf)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expect err %v, got %v", tt.err, err)
+			}
+			if tt.err != nil {
+				if buf.String() != "" {
+					t.Fatalf("expect nothing written, got %q", buf.String())
+				}
+				return
+			}
+			if buf.String() != tt.want {
+				t.Fatalf("got  %s\nwant %s", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+type unknownDialect struct {
+	dbr.Dialect
+}
+
+func TestUpsertExec(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+	if _, err := conn.Exec("CREATE TABLE shop_tab (shop_id INTEGER PRIMARY KEY, buyer_num INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+
+	sess := conn.NewSessionContext(context.Background())
+	for _, num := range []int{1, 2} {
+		u := sess.UpsertInto("shop_tab").OnConflict("shop_id")
+		u.Columns("shop_id", "buyer_num").Values(7, num)
+		if _, err := sess.Exec(u); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var num int
+	if err := sess.LoadOne(sess.Select("buyer_num").From("shop_tab").Where("shop_id = ?", 7), &num); err != nil {
+		t.Fatal(err)
+	}
+	if num != 2 {
+		t.Fatalf("expect buyer_num 2, got %d", num)
+	}
+}
+
+func TestUpdateVersion(t *testing.T) {
+	conn := dbtest.NewSQLite(t)
+	if _, err := conn.Exec("CREATE TABLE shop_tab (id INTEGER, name TEXT, version INTEGER)"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := conn.Exec("INSERT INTO shop_tab VALUES (1, 'old', 3)"); err != nil {
+		t.Fatal(err)
+	}
+
+	sess := conn.NewSessionContext(context.Background())
+	update := func(version int64) error {
+		return sess.UpdateVersion(sess.Update("shop_tab").Set("name", "new").Where("id = ?", 1), "version", version)
+	}
+
+	if err := update(3); err != nil {
+		t.Fatal(err)
+	}
+	if err := update(3); !errors.Is(err, db.ErrConcurrentModification) {
+		t.Fatalf("expect ErrConcurrentModification, got %v", err)
+	}
+
+	var version int64
+	if err := sess.LoadOne(sess.Select("version").From("shop_tab").Where("id = ?", 1), &version); err != nil {
+		t.Fatal(err)
+	}
+	if version != 4 {
+		t.Fatalf("expect version 4, got %d", version)
+	}
+}
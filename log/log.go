@@ -17,7 +17,8 @@ type Log interface {
 type Fields map[string]interface{}
 
 var (
-	logger Log
+	// 未调用 InitLogger 时输出到标准库 log, 避免空指针
+	logger Log = stdLogger{}
 )
 
 func GetInstance() Log {
@@ -28,6 +29,14 @@ func InitLogger(l *Option) {
 	logger = NewLogger(l)
 }
 
+// SetLogger 替换全局 logger, l 为 nil 时恢复默认
+func SetLogger(l Log) {
+	if l == nil {
+		l = stdLogger{}
+	}
+	logger = l
+}
+
 func Debug(msg string) {
 	logger.Debug(msg)
 }
@@ -0,0 +1,55 @@
+package log
+
+import (
+	"fmt"
+	stdlog "log"
+)
+
+// stdLogger 未调用 InitLogger 时的默认实现, 输出到标准库 log (stderr)
+type stdLogger struct{}
+
+func (stdLogger) Debug(msg string) {
+	stdlog.Output(3, "[debug] "+msg)
+}
+
+func (stdLogger) Debugf(format string, a ...interface{}) {
+	stdlog.Output(3, "[debug] "+fmt.Sprintf(format, a...))
+}
+
+func (stdLogger) Info(msg string) {
+	stdlog.Output(3, "[info] "+msg)
+}
+
+func (stdLogger) Infof(format string, a ...interface{}) {
+	stdlog.Output(3, "[info] "+fmt.Sprintf(format, a...))
+}
+
+func (stdLogger) Warn(msg string) {
+	stdlog.Output(3, "[warn] "+msg)
+}
+
+func (stdLogger) Warnf(format string, a ...interface{}) {
+	stdlog.Output(3, "[warn] "+fmt.Sprintf(format, a...))
+}
+
+func (stdLogger) Error(msg string) {
+	stdlog.Output(3, "[error] "+msg)
+}
+
+func (stdLogger) Errorf(format string, a ...interface{}) {
+	stdlog.Output(3, "[error] "+fmt.Sprintf(format, a...))
+}
+
+type nopLogger struct{}
+
+// NopLogger 丢弃所有日志, 可用于测试
+var NopLogger Log = nopLogger{}
+
+func (nopLogger) Debug(msg string)                       {}
+func (nopLogger) Debugf(format string, a ...interface{}) {}
+func (nopLogger) Info(msg string)                        {}
+func (nopLogger) Infof(format string, a ...interface{})  {}
+func (nopLogger) Warn(msg string)                        {}
+func (nopLogger) Warnf(format string, a ...interface{})  {}
+func (nopLogger) Error(msg string)                       {}
+func (nopLogger) Errorf(format string, a ...interface{}) {}
@@ -0,0 +1,70 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/sujunbo/micro/util/breaker"
+)
+
+// ErrCircuitOpen 目标 host 熔断期间直接失败, 不再等待超时
+var ErrCircuitOpen = breaker.ErrOpen
+
+type hostBreakers struct {
+	service string
+	opts    []breaker.Option
+
+	mutex    sync.Mutex
+	breakers map[string]*breaker.Breaker
+}
+
+func (b *hostBreakers) get(host string) *breaker.Breaker {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if cb, ok := b.breakers[host]; ok {
+		return cb
+	}
+
+	name := host
+	if b.service != "" {
+		name = b.service + ":" + host
+	}
+	cb := breaker.New(append(b.opts, breaker.WithName(name))...)
+	b.breakers[host] = cb
+	return cb
+}
+
+// BreakerMiddleware 按 host 熔断, 连接错误/超时与 5xx 计为失败, 状态变化由 breaker 记录日志与指标
+func BreakerMiddleware(service string, opts ...breaker.Option) Middleware {
+	b := &hostBreakers{
+		service:  service,
+		opts:     opts,
+		breakers: map[string]*breaker.Breaker{},
+	}
+
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			done, err := b.get(req.URL.Host).Allow()
+			if err != nil {
+				return nil, err
+			}
+
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			done(isFailure(resp, err), time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// isFailure 调用方取消不计入熔断
+func isFailure(resp *http.Response, err error) bool {
+	if err != nil {
+		return !errors.Is(err, context.Canceled)
+	}
+	return resp.StatusCode >= http.StatusInternalServerError
+}
@@ -0,0 +1,95 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+	"github.com/sujunbo/micro/util/breaker"
+)
+
+// statusServer 返回 code, slow 路径等待请求取消
+func statusServer(t *testing.T, code int) (*httptest.Server, *int64) {
+	var hits int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt64(&hits, 1)
+		if r.URL.Path == "/slow" {
+			<-r.Context().Done()
+			return
+		}
+		w.WriteHeader(code)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &hits
+}
+
+func newBreakerClient(t *testing.T) *xhttp.HttpClient {
+	return xhttp.NewHttpClient(
+		xhttp.WithServiceName(t.Name()),
+		xhttp.WithBreaker(breaker.WithMinRequests(2), breaker.WithOpenTimeout(time.Minute)),
+	)
+}
+
+func TestBreakerMiddleware(t *testing.T) {
+	c := newBreakerClient(t)
+	ctx := context.Background()
+	failing, failingHits := statusServer(t, http.StatusServiceUnavailable)
+	healthy, _ := statusServer(t, http.StatusOK)
+
+	for i := 0; i < 2; i++ {
+		var he *xhttp.HTTPError
+		if err := c.Get(ctx, failing.URL, nil); !errors.As(err, &he) {
+			t.Fatalf("expect HTTPError, got %v", err)
+		}
+	}
+
+	// 熔断后直接失败, 不再请求上游
+	if err := c.Get(ctx, failing.URL, nil); !errors.Is(err, xhttp.ErrCircuitOpen) {
+		t.Fatalf("expect ErrCircuitOpen, got %v", err)
+	}
+	if n := atomic.LoadInt64(failingHits); n != 2 {
+		t.Fatalf("expect 2 upstream hits, got %d", n)
+	}
+
+	// 其他 host 不受影响
+	if err := c.Get(ctx, healthy.URL, nil); err != nil {
+		t.Fatalf("expect other host allowed, got %v", err)
+	}
+}
+
+func TestBreakerMiddlewareIgnores(t *testing.T) {
+	c := newBreakerClient(t)
+
+	t.Run("4xx", func(t *testing.T) {
+		srv, hits := statusServer(t, http.StatusNotFound)
+		for i := 0; i < 5; i++ {
+			var he *xhttp.HTTPError
+			if err := c.Get(context.Background(), srv.URL, nil); !errors.As(err, &he) || he.StatusCode != http.StatusNotFound {
+				t.Fatalf("expect 404, got %v", err)
+			}
+		}
+		if n := atomic.LoadInt64(hits); n != 5 {
+			t.Fatalf("expect 5 upstream hits, got %d", n)
+		}
+	})
+
+	t.Run("caller cancel", func(t *testing.T) {
+		srv, _ := statusServer(t, http.StatusOK)
+		for i := 0; i < 3; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(20*time.Millisecond, cancel)
+			if err := c.Get(ctx, srv.URL+"/slow", nil); !errors.Is(err, xhttp.ErrRequestCanceled) {
+				t.Fatalf("expect ErrRequestCanceled, got %v", err)
+			}
+			cancel()
+		}
+		if err := c.Get(context.Background(), srv.URL, nil); err != nil {
+			t.Fatalf("expect allowed after cancels, got %v", err)
+		}
+	})
+}
@@ -3,91 +3,250 @@ package http
 import (
 	"bytes"
 	"context"
-	"encoding/json"
-	"fmt"
+	"errors"
 	"io"
 	"io/ioutil"
 	"net"
 	"net/http"
+	"strings"
 	"time"
 )
 
+var (
+	ErrRequestTimeout  = errors.New("http request timeout")
+	ErrRequestCanceled = errors.New("http request canceled")
+)
+
 type HttpClient struct {
 	client  *http.Client
 	options options
 }
 
+// Response 原始响应, 不检查状态码也不解析 body
+type Response struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (h *HttpClient) Get(ctx context.Context, url string, v interface{}, opts ...RequestOption) (err error) {
+	return h.Do(ctx, "GET", url, nil, v, opts...)
+}
+
 func (h *HttpClient) Post(ctx context.Context, url string, body interface{}, v interface{}, opts ...RequestOption) (err error) {
-	reader, err := bodyReader(body)
-	if err != nil {
-		return
-	}
+	return h.Do(ctx, "POST", url, body, v, opts...)
+}
 
-	return h.handle("POST", url, reader, v, opts...)
+func (h *HttpClient) Put(ctx context.Context, url string, body interface{}, v interface{}, opts ...RequestOption) (err error) {
+	return h.Do(ctx, "PUT", url, body, v, opts...)
 }
 
-func bodyReader(body interface{}) (io.Reader, error) {
-	if _, ok := body.(io.Reader); ok {
-		return body.(io.Reader), nil
-	}
+func (h *HttpClient) Patch(ctx context.Context, url string, body interface{}, v interface{}, opts ...RequestOption) (err error) {
+	return h.Do(ctx, "PATCH", url, body, v, opts...)
+}
+
+func (h *HttpClient) Delete(ctx context.Context, url string, v interface{}, opts ...RequestOption) (err error) {
+	return h.Do(ctx, "DELETE", url, nil, v, opts...)
+}
 
-	buf, err := json.Marshal(body)
+// Head 返回响应头
+func (h *HttpClient) Head(ctx context.Context, url string, opts ...RequestOption) (http.Header, error) {
+	resp, err := h.DoRaw(ctx, "HEAD", url, nil, opts...)
 	if err != nil {
 		return nil, err
 	}
-	return bytes.NewBuffer(buf), nil
+	return resp.Header, nil
 }
 
-func (h *HttpClient) Get(ctx context.Context, url string, v interface{}, opts ...RequestOption) (err error) {
-	return h.handle("GET", url, nil, v, opts...)
+// Do 发送请求并将响应按其 Content-Type 解析到 v, v 为 nil 时忽略响应 body;
+// body 为 nil 时不带请求体, io.Reader 原样发送, *Multipart 按 multipart/form-data 上传,
+// 其他类型按 RequestOptions.ContentType 对应的 Codec 编码, 默认 json
+func (h *HttpClient) Do(ctx context.Context, method string, url string, body interface{}, v interface{}, opts ...RequestOption) (err error) {
+	return h.handle(ctx, method, url, body, v, opts...)
 }
 
-func (h *HttpClient) do(method string, url string, reader io.Reader, header http.Header) (resp *http.Response, err error) {
-	req, err := http.NewRequest(method, url, reader)
+// DoRaw 与 Do 相同, 但返回原始的状态码/响应头/body, 由调用方自行处理
+func (h *HttpClient) DoRaw(ctx context.Context, method string, url string, body interface{}, opts ...RequestOption) (resp *Response, err error) {
+	opt, reader, err := h.request(body, opts)
 	if err != nil {
-		return nil, err
+		return
+	}
+
+	ctx, cancel := opt.context(ctx)
+	defer cancel()
+	defer func() {
+		err = ctxError(ctx, err)
+	}()
+
+	r, err := h.do(ctx, method, url, reader, &opt)
+	if err != nil {
+		return
+	}
+
+	defer closeBody(r)
+	buf, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		return
 	}
 
-	req.Header = header
-	return h.client.Do(req)
+	return &Response{StatusCode: r.StatusCode, Header: r.Header, Body: buf}, nil
 }
 
-func (h *HttpClient) handle(method string, url string, reader io.Reader, v interface{}, opts ...RequestOption) (err error) {
-	opt := RequestOptions{
-		ContentType: "application/json",
+// request 合并请求选项并编码 body, Content-Type 以实际编码为准
+func (h *HttpClient) request(body interface{}, opts []RequestOption) (opt RequestOptions, reader io.Reader, err error) {
+	opt = RequestOptions{
+		ContentType: JSONCodec.ContentType(),
 		Header:      http.Header{},
+		Timeout:     h.options.timeout,
 	}
 	for _, o := range opts {
 		o(&opt)
 	}
 
+	// 未注册 codec 的类型(如 text/plain): []byte/string 原样发送, 其他按 json 编码, 与引入 codec 前一致
+	codec := opt.codec()
+	switch b := body.(type) {
+	case nil:
+	case *Multipart:
+		reader, opt.ContentType, err = b.encode()
+	case io.Reader:
+		reader = b
+	case []byte:
+		if codec == nil {
+			reader = bytes.NewReader(b)
+		} else {
+			reader, err = encodeBody(codec, body)
+		}
+	case string:
+		if codec == nil {
+			reader = strings.NewReader(b)
+		} else {
+			reader, err = encodeBody(codec, body)
+		}
+	default:
+		if codec == nil {
+			codec = JSONCodec
+		}
+		reader, err = encodeBody(codec, body)
+	}
+	if err != nil {
+		return
+	}
+
 	opt.Header.Set("Content-Type", opt.ContentType)
+	return
+}
+
+func encodeBody(codec Codec, body interface{}) (io.Reader, error) {
+	buf, err := codec.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(buf), nil
+}
 
-	resp, err := h.do(method, url, reader, opt.Header)
+func (h *HttpClient) do(ctx context.Context, method string, url string, reader io.Reader, opt *RequestOptions) (resp *http.Response, err error) {
+	url, err = opt.url(url)
 	if err != nil {
 		return
 	}
 
+	send := func(reader io.Reader) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, url, reader)
+		if err != nil {
+			return nil, err
+		}
+
+		req.Header = opt.Header
+		return h.client.Do(req)
+	}
+
+	if h.options.retry == nil || !opt.retryable(method) {
+		return send(reader)
+	}
+	return h.options.retry.do(ctx, url, reader, send)
+}
+
+func (h *HttpClient) handle(ctx context.Context, method string, url string, body interface{}, v interface{}, opts ...RequestOption) (err error) {
+	opt, reader, err := h.request(body, opts)
+	if err != nil {
+		return
+	}
+
+	ctx, cancel := opt.context(ctx)
+	defer cancel()
+	defer func() {
+		err = ctxError(ctx, err)
+	}()
+
+	resp, err := h.do(ctx, method, url, reader, &opt)
+	if err != nil {
+		return
+	}
+	defer closeBody(resp)
+
 	if opt.RespHandler != nil {
 		return opt.RespHandler(resp)
 	}
 
-	if resp.StatusCode == http.StatusNoContent {
-		return
+	if !success(resp.StatusCode) {
+		return newHTTPError(url, resp)
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("http code:%v url:%v", resp.StatusCode, url)
+	if v == nil || resp.StatusCode == http.StatusNoContent {
+		return
 	}
 
-	defer resp.Body.Close()
-	body, err := ioutil.ReadAll(resp.Body)
-	if err != nil {
+	buf, err := ioutil.ReadAll(resp.Body)
+	if err != nil || len(buf) == 0 {
 		return
 	}
 
-	err = json.Unmarshal(body, v)
-	return
+	// 响应 Content-Type 未注册时按请求的 codec 解析
+	codec := codecFor(resp.Header.Get("Content-Type"))
+	if codec == nil {
+		codec = opt.codec()
+	}
+	if codec == nil {
+		codec = JSONCodec
+	}
+	return codec.Unmarshal(buf, v)
+}
+
+// ctxError 区分超时与调用方取消, 可用 errors.Is 匹配;
+// 原始错误(*url.Error、net.Error 等)保留在错误链中, 可用 errors.As 取出
+func ctxError(ctx context.Context, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded), errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return &requestError{kind: ErrRequestTimeout, cause: context.DeadlineExceeded, err: err}
+	case errors.Is(err, context.Canceled), errors.Is(ctx.Err(), context.Canceled):
+		return &requestError{kind: ErrRequestCanceled, cause: context.Canceled, err: err}
+	}
+	return err
+}
+
+// requestError 匹配 ErrRequestTimeout/ErrRequestCanceled 与对应的 context 错误,
+// 即使传输层返回的错误未包装 ctx.Err()
+type requestError struct {
+	kind  error
+	cause error
+	err   error
+}
+
+func (e *requestError) Error() string {
+	return e.kind.Error() + ": " + e.err.Error()
+}
+
+func (e *requestError) Is(target error) bool {
+	return target == e.kind || target == e.cause
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
 }
 
 func NewHttpClient(opts ...Option) *HttpClient {
@@ -98,14 +257,23 @@ func NewHttpClient(opts ...Option) *HttpClient {
 		idleConnTimeout:     90 * time.Second,
 		keepAlive:           30 * time.Second,
 		tlsHandshakeTimeout: 10 * time.Second,
+		propagation:         DefaultPropagationKeys,
 	}
 	for _, o := range opts {
 		o(&opt)
 	}
 
+	middlewares := opt.middlewares
+	if len(opt.propagation) > 0 {
+		middlewares = append([]Middleware{PropagationMiddleware(opt.propagation...)}, middlewares...)
+	}
+	if opt.breaker {
+		middlewares = append(middlewares, BreakerMiddleware(opt.name, opt.breakerOpts...))
+	}
+
 	return &HttpClient{
 		client: &http.Client{
-			Transport: &http.Transport{
+			Transport: chainMiddlewares(&http.Transport{
 				Proxy: http.ProxyFromEnvironment,
 				DialContext: (&net.Dialer{
 					Timeout:   opt.dialTimeout,
@@ -116,8 +284,7 @@ func NewHttpClient(opts ...Option) *HttpClient {
 				IdleConnTimeout:       opt.idleConnTimeout,
 				TLSHandshakeTimeout:   opt.tlsHandshakeTimeout,
 				ExpectContinueTimeout: 1 * time.Second,
-			},
-			Timeout: opt.timeout,
+			}, middlewares...),
 		},
 		options: opt,
 	}
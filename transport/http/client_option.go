@@ -1,8 +1,12 @@
 package http
 
 import (
+	"context"
 	"net/http"
+	"net/url"
 	"time"
+
+	"github.com/sujunbo/micro/util/breaker"
 )
 
 type options struct {
@@ -13,6 +17,11 @@ type options struct {
 	keepAlive           time.Duration
 	tlsHandshakeTimeout time.Duration
 	name                string
+	retry               *RetryPolicy
+	middlewares         []Middleware
+	propagation         []string
+	breaker             bool
+	breakerOpts         []breaker.Option
 }
 
 type Option func(*options)
@@ -23,6 +32,7 @@ func WithMaxConnectionNum(maxConnectionNum int) Option {
 	}
 }
 
+// WithTimeout 默认的单次请求超时, 可被 WithRequestTimeout 覆盖
 func WithTimeout(duration time.Duration) Option {
 	return func(opt *options) {
 		opt.timeout = duration
@@ -59,10 +69,93 @@ func WithServiceName(name string) Option {
 	}
 }
 
+// WithRetry 对失败的请求按 policy 重试, 默认只重试幂等方法
+func WithRetry(policy RetryPolicy) Option {
+	return func(opt *options) {
+		policy.apply()
+		opt.retry = &policy
+	}
+}
+
+// WithMiddleware 追加 client 中间件, 如 WithMiddleware(LoggingMiddleware(time.Second), MetricsMiddleware("user"))
+func WithMiddleware(middlewares ...Middleware) Option {
+	return func(opt *options) {
+		opt.middlewares = append(opt.middlewares, middlewares...)
+	}
+}
+
+// WithPropagation 设置透传到下游的入站 header, 默认 DefaultPropagationKeys, 不传 key 时关闭透传;
+// 调用内部服务时可加入 x-user-id, 不要对第三方开启
+func WithPropagation(keys ...string) Option {
+	return func(opt *options) {
+		opt.propagation = keys
+	}
+}
+
+// WithBreaker 对每个 host 单独熔断, 熔断期间请求返回 ErrCircuitOpen, 重试时每次尝试分别计数
+func WithBreaker(opts ...breaker.Option) Option {
+	return func(opt *options) {
+		opt.breaker = true
+		opt.breakerOpts = opts
+	}
+}
+
 type RequestOptions struct {
 	ContentType string
+	// 为空时按 ContentType 从已注册的 codec 中选择
+	Codec       Codec
 	Header      http.Header
 	RespHandler func(*http.Response) error
+	// 覆盖 client 的 WithTimeout, 0 表示仅受 ctx 控制
+	Timeout time.Duration
+	// 追加到 url 的查询参数
+	Query url.Values
+	// 覆盖按方法幂等性判断的重试开关, nil 表示按方法判断
+	Retryable *bool
+}
+
+func (opt *RequestOptions) codec() Codec {
+	if opt.Codec != nil {
+		return opt.Codec
+	}
+	return codecFor(opt.ContentType)
+}
+
+func (opt *RequestOptions) retryable(method string) bool {
+	if opt.Retryable != nil {
+		return *opt.Retryable
+	}
+	return idempotent(method)
+}
+
+func (opt *RequestOptions) context(ctx context.Context) (context.Context, context.CancelFunc) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if opt.Timeout > 0 {
+		return context.WithTimeout(ctx, opt.Timeout)
+	}
+	return ctx, func() {}
+}
+
+func (opt *RequestOptions) url(rawURL string) (string, error) {
+	if len(opt.Query) == 0 {
+		return rawURL, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	q := u.Query()
+	for k, vs := range opt.Query {
+		for _, v := range vs {
+			q.Add(k, v)
+		}
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
 }
 
 type RequestOption func(*RequestOptions)
@@ -73,6 +166,14 @@ func WithContentType(contentType string) RequestOption {
 	}
 }
 
+// WithCodec 指定请求 body 的编码, 并将 Content-Type 设置为 codec.ContentType()
+func WithCodec(codec Codec) RequestOption {
+	return func(opt *RequestOptions) {
+		opt.Codec = codec
+		opt.ContentType = codec.ContentType()
+	}
+}
+
 func WithHeader(m http.Header) RequestOption {
 	return func(opt *RequestOptions) {
 		opt.Header = m
@@ -92,3 +193,39 @@ func WithResponse(fn func(*http.Response) error) RequestOption {
 		opt.RespHandler = fn
 	}
 }
+
+func WithRequestTimeout(duration time.Duration) RequestOption {
+	return func(opt *RequestOptions) {
+		opt.Timeout = duration
+	}
+}
+
+// WithQuery 追加查询参数, 与 url 中已有的参数合并
+func WithQuery(query url.Values) RequestOption {
+	return func(opt *RequestOptions) {
+		if opt.Query == nil {
+			opt.Query = url.Values{}
+		}
+		for k, vs := range query {
+			opt.Query[k] = append(opt.Query[k], vs...)
+		}
+	}
+}
+
+func WithQuerySet(kv ...string) RequestOption {
+	return func(opt *RequestOptions) {
+		if opt.Query == nil {
+			opt.Query = url.Values{}
+		}
+		for i := 0; i < len(kv)-1; i += 2 {
+			opt.Query.Set(kv[i], kv[i+1])
+		}
+	}
+}
+
+// WithRetryable 单次请求是否允许重试, 如对带幂等键的 POST 开启重试
+func WithRetryable(retryable bool) RequestOption {
+	return func(opt *RequestOptions) {
+		opt.Retryable = &retryable
+	}
+}
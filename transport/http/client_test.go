@@ -0,0 +1,200 @@
+package http_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+// slowServer 在 delay 后返回 200, 请求断开时提前返回
+func slowServer(t *testing.T, delay time.Duration) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestRequestTimeout(t *testing.T) {
+	srv := slowServer(t, 200*time.Millisecond)
+
+	tests := []struct {
+		name    string
+		client  time.Duration
+		request []xhttp.RequestOption
+		ctx     func() (context.Context, context.CancelFunc)
+		want    error
+	}{
+		{name: "client timeout", client: 50 * time.Millisecond, want: xhttp.ErrRequestTimeout},
+		{name: "request timeout overrides longer", client: time.Minute, request: []xhttp.RequestOption{xhttp.WithRequestTimeout(50 * time.Millisecond)}, want: xhttp.ErrRequestTimeout},
+		{name: "request timeout overrides shorter", client: 50 * time.Millisecond, request: []xhttp.RequestOption{xhttp.WithRequestTimeout(time.Second)}},
+		{
+			name:    "caller deadline",
+			request: []xhttp.RequestOption{xhttp.WithRequestTimeout(0)},
+			ctx: func() (context.Context, context.CancelFunc) {
+				return context.WithTimeout(context.Background(), 50*time.Millisecond)
+			},
+			want: xhttp.ErrRequestTimeout,
+		},
+		{
+			name: "caller cancel",
+			ctx: func() (context.Context, context.CancelFunc) {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				return ctx, cancel
+			},
+			want: xhttp.ErrRequestCanceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []xhttp.Option
+			if tt.client > 0 {
+				opts = append(opts, xhttp.WithTimeout(tt.client))
+			}
+			c := xhttp.NewHttpClient(opts...)
+
+			ctx, cancel := context.Background(), context.CancelFunc(func() {})
+			if tt.ctx != nil {
+				ctx, cancel = tt.ctx()
+			}
+			defer cancel()
+
+			err := c.Get(ctx, srv.URL, nil, tt.request...)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("expect success, got %v", err)
+				}
+				return
+			}
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("expect %v, got %v", tt.want, err)
+			}
+
+			// 原始错误与 context 错误仍在错误链中
+			var urlErr *url.Error
+			if !errors.As(err, &urlErr) {
+				t.Fatalf("expect *url.Error in chain, got %T", errors.Unwrap(err))
+			}
+			if tt.want == xhttp.ErrRequestTimeout {
+				var netErr net.Error
+				if !errors.As(err, &netErr) || !netErr.Timeout() {
+					t.Fatalf("expect net.Error timeout in chain, got %v", err)
+				}
+				if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) || errors.Is(err, xhttp.ErrRequestCanceled) {
+					t.Fatalf("timeout matches wrong sentinels: %v", err)
+				}
+			} else if !errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, xhttp.ErrRequestTimeout) {
+				t.Fatalf("cancel matches wrong sentinels: %v", err)
+			}
+		})
+	}
+}
+
+type echo struct {
+	Method string     `json:"method"`
+	Body   string     `json:"body"`
+	Query  url.Values `json:"query"`
+}
+
+// echoServer 以 json 返回收到的方法, body 与查询参数
+func echoServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Method", r.Method)
+		if r.Method == http.MethodHead {
+			return
+		}
+		json.NewEncoder(w).Encode(echo{Method: r.Method, Body: string(b), Query: r.URL.Query()})
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVerbs(t *testing.T) {
+	srv := echoServer(t)
+	c := xhttp.NewHttpClient()
+	ctx := context.Background()
+	body := payload{Name: "a"}
+
+	tests := []struct {
+		method string
+		do     func(v interface{}) error
+		body   string
+	}{
+		{http.MethodGet, func(v interface{}) error { return c.Get(ctx, srv.URL, v) }, ""},
+		{http.MethodPost, func(v interface{}) error { return c.Post(ctx, srv.URL, body, v) }, `{"name":"a"}`},
+		{http.MethodPut, func(v interface{}) error { return c.Put(ctx, srv.URL, body, v) }, `{"name":"a"}`},
+		{http.MethodPatch, func(v interface{}) error { return c.Patch(ctx, srv.URL, body, v) }, `{"name":"a"}`},
+		{http.MethodDelete, func(v interface{}) error { return c.Delete(ctx, srv.URL, v) }, ""},
+		{"PROPFIND", func(v interface{}) error {
+			return c.Do(ctx, "PROPFIND", srv.URL, []byte("raw"), v, xhttp.WithContentType("text/plain"))
+		}, "raw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			var got echo
+			if err := tt.do(&got); err != nil {
+				t.Fatal(err)
+			}
+			if got.Method != tt.method || got.Body != tt.body {
+				t.Fatalf("expect %v %q, got %v %q", tt.method, tt.body, got.Method, got.Body)
+			}
+		})
+	}
+
+	header, err := c.Head(ctx, srv.URL)
+	if err != nil || header.Get("X-Method") != http.MethodHead {
+		t.Fatalf("expect HEAD response header, got %v %v", header, err)
+	}
+}
+
+func TestDoRaw(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Reason", "maintenance")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "try later")
+	}))
+	defer srv.Close()
+
+	// 非 2xx 不返回错误, 由调用方处理
+	resp, err := xhttp.NewHttpClient().DoRaw(context.Background(), http.MethodGet, srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusServiceUnavailable || resp.Header.Get("X-Reason") != "maintenance" || string(resp.Body) != "try later" {
+		t.Fatalf("unexpected raw response %+v", resp)
+	}
+}
+
+func TestWithQuery(t *testing.T) {
+	srv := echoServer(t)
+
+	var got echo
+	err := xhttp.NewHttpClient().Get(context.Background(), srv.URL+"?a=1&shop_id=7", &got,
+		xhttp.WithQuery(url.Values{"a": {"2"}, "b": {"x y"}}),
+		xhttp.WithQuery(url.Values{"b": {"z"}}),
+		xhttp.WithQuerySet("c", "3"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := url.Values{"a": {"1", "2"}, "b": {"x y", "z"}, "c": {"3"}, "shop_id": {"7"}}
+	if !reflect.DeepEqual(got.Query, want) {
+		t.Fatalf("expect query %v, got %v", want, got.Query)
+	}
+}
@@ -0,0 +1,229 @@
+package http
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"mime"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/golang/protobuf/jsonpb"
+	"github.com/golang/protobuf/proto"
+)
+
+var ErrCodecType = errors.New("http codec type not support")
+
+// Codec 请求/响应 body 的编解码, 请求按 RequestOptions.ContentType 选择, 响应按响应的 Content-Type 选择
+type Codec interface {
+	ContentType() string
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	// JSONCodec proto.Message 使用 jsonpb, 与服务端 defaultCodec 一致, 其他类型使用 encoding/json
+	JSONCodec Codec = jsonCodec{}
+	// JSONPBCodec 只支持 proto.Message
+	JSONPBCodec Codec = jsonpbCodec{}
+	ProtoCodec  Codec = protoCodec{}
+	FormCodec   Codec = formCodec{}
+	XMLCodec    Codec = xmlCodec{}
+)
+
+var (
+	codecMutex sync.RWMutex
+	codecs     = map[string]Codec{}
+)
+
+func init() {
+	RegisterCodec(JSONCodec)
+	RegisterCodec(ProtoCodec)
+	RegisterCodec(FormCodec)
+	RegisterCodec(XMLCodec)
+	registerCodec("application/protobuf", ProtoCodec)
+	registerCodec("text/xml", XMLCodec)
+}
+
+// RegisterCodec 按 codec.ContentType() 注册, 已存在时覆盖
+func RegisterCodec(codec Codec) {
+	registerCodec(codec.ContentType(), codec)
+}
+
+func registerCodec(contentType string, codec Codec) {
+	codecMutex.Lock()
+	defer codecMutex.Unlock()
+
+	codecs[contentType] = codec
+}
+
+// codecFor 忽略 charset 等参数, application/vnd.x+json 等带 +json/+xml 后缀的类型按 json/xml 处理;
+// 未注册时返回 nil
+func codecFor(contentType string) Codec {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil
+	}
+
+	codecMutex.RLock()
+	codec, ok := codecs[mediaType]
+	codecMutex.RUnlock()
+	if ok {
+		return codec
+	}
+
+	switch {
+	case strings.HasSuffix(mediaType, "+json"):
+		return JSONCodec
+	case strings.HasSuffix(mediaType, "+xml"):
+		return XMLCodec
+	}
+	return nil
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	if _, ok := v.(proto.Message); ok {
+		return JSONPBCodec.Marshal(v)
+	}
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	if _, ok := v.(proto.Message); ok {
+		return JSONPBCodec.Unmarshal(data, v)
+	}
+	return json.Unmarshal(data, v)
+}
+
+type jsonpbCodec struct{}
+
+func (jsonpbCodec) ContentType() string {
+	return "application/json"
+}
+
+func (jsonpbCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: jsonpb %T", ErrCodecType, v)
+	}
+
+	var buf bytes.Buffer
+	err := (&jsonpb.Marshaler{EmitDefaults: true}).Marshal(&buf, m)
+	return buf.Bytes(), err
+}
+
+func (jsonpbCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: jsonpb %T", ErrCodecType, v)
+	}
+	return (&jsonpb.Unmarshaler{AllowUnknownFields: true}).Unmarshal(bytes.NewReader(data), m)
+}
+
+type protoCodec struct{}
+
+func (protoCodec) ContentType() string {
+	return "application/x-protobuf"
+}
+
+func (protoCodec) Marshal(v interface{}) ([]byte, error) {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("%w: protobuf %T", ErrCodecType, v)
+	}
+	return proto.Marshal(m)
+}
+
+func (protoCodec) Unmarshal(data []byte, v interface{}) error {
+	m, ok := v.(proto.Message)
+	if !ok {
+		return fmt.Errorf("%w: protobuf %T", ErrCodecType, v)
+	}
+	return proto.Unmarshal(data, m)
+}
+
+type formCodec struct{}
+
+func (formCodec) ContentType() string {
+	return "application/x-www-form-urlencoded"
+}
+
+// Marshal 支持 url.Values, map[string]string, map[string][]string 及带 form tag 的 struct
+func (formCodec) Marshal(v interface{}) ([]byte, error) {
+	values := url.Values{}
+	switch vv := v.(type) {
+	case url.Values:
+		values = vv
+	case map[string][]string:
+		values = vv
+	case map[string]string:
+		for k, s := range vv {
+			values.Set(k, s)
+		}
+	default:
+		rv := reflect.Indirect(reflect.ValueOf(v))
+		if rv.Kind() != reflect.Struct {
+			return nil, fmt.Errorf("%w: form %T", ErrCodecType, v)
+		}
+
+		for i := 0; i < rv.NumField(); i++ {
+			field := rv.Type().Field(i)
+			name := field.Tag.Get("form")
+			if name == "-" || field.PkgPath != "" {
+				continue
+			}
+			if name == "" {
+				name = field.Name
+			}
+			values.Set(name, fmt.Sprint(rv.Field(i).Interface()))
+		}
+	}
+	return []byte(values.Encode()), nil
+}
+
+// Unmarshal 支持 *url.Values, *map[string]string, *map[string][]string
+func (formCodec) Unmarshal(data []byte, v interface{}) error {
+	values, err := url.ParseQuery(string(data))
+	if err != nil {
+		return err
+	}
+
+	switch vv := v.(type) {
+	case *url.Values:
+		*vv = values
+	case *map[string][]string:
+		*vv = values
+	case *map[string]string:
+		*vv = map[string]string{}
+		for k := range values {
+			(*vv)[k] = values.Get(k)
+		}
+	default:
+		return fmt.Errorf("%w: form %T", ErrCodecType, v)
+	}
+	return nil
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) ContentType() string {
+	return "application/xml"
+}
+
+func (xmlCodec) Marshal(v interface{}) ([]byte, error) {
+	return xml.Marshal(v)
+}
+
+func (xmlCodec) Unmarshal(data []byte, v interface{}) error {
+	return xml.Unmarshal(data, v)
+}
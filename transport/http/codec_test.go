@@ -0,0 +1,72 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+type payload struct {
+	Name string `json:"name" xml:"name"`
+}
+
+func TestRequestCodecFallback(t *testing.T) {
+	var gotBody, gotType string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		gotBody, gotType = string(b), r.Header.Get("Content-Type")
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		contentType string
+		body        interface{}
+		want        string
+	}{
+		{"application/json", payload{Name: "a"}, `{"name":"a"}`},
+		{"text/plain", payload{Name: "a"}, `{"name":"a"}`},
+		{"application/vnd.x+json", payload{Name: "a"}, `{"name":"a"}`},
+		{"application/vnd.x+json; charset=utf-8", payload{Name: "a"}, `{"name":"a"}`},
+		{"application/atom+xml", payload{Name: "a"}, `<payload><name>a</name></payload>`},
+		{"text/plain", "hello", `hello`},
+		{"application/octet-stream", []byte("raw"), `raw`},
+		{"application/json", "hello", `"hello"`},
+	}
+	c := xhttp.NewHttpClient()
+	for _, tt := range tests {
+		if err := c.Post(context.Background(), srv.URL, tt.body, nil, xhttp.WithContentType(tt.contentType)); err != nil {
+			t.Fatalf("post %v: %v", tt.contentType, err)
+		}
+		if gotBody != tt.want || gotType != tt.contentType {
+			t.Errorf("post %v %#v: got %q (%v), want %q", tt.contentType, tt.body, gotBody, gotType, tt.want)
+		}
+	}
+}
+
+func TestResponseCodecSuffix(t *testing.T) {
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/problem+json", `{"name":"a"}`},
+		{"application/vnd.x+xml; charset=utf-8", `<payload><name>a</name></payload>`},
+		{"text/plain", `{"name":"a"}`},
+	}
+	for _, tt := range tests {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", tt.contentType)
+			w.Write([]byte(tt.body))
+		}))
+
+		var v payload
+		err := xhttp.NewHttpClient().Get(context.Background(), srv.URL, &v)
+		srv.Close()
+		if err != nil || v.Name != "a" {
+			t.Errorf("decode %v: got %+v err:%v", tt.contentType, v, err)
+		}
+	}
+}
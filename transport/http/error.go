@@ -0,0 +1,52 @@
+package http
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+const (
+	// 错误响应最多保留的 body 字节数
+	maxErrorBody = 4 << 10
+	// 关闭前最多读取丢弃的字节数, 超过则放弃复用连接
+	maxDrainBody = 64 << 10
+)
+
+// HTTPError 非 2xx 响应, 可用 errors.As 取出状态码与响应内容
+type HTTPError struct {
+	StatusCode int
+	Header     http.Header
+	// 截断到 4KB
+	Body []byte
+	// 实际请求的 url, 含 WithQuery 追加的参数
+	URL string
+}
+
+func (e *HTTPError) Error() string {
+	return fmt.Sprintf("http code:%v url:%v body:%s", e.StatusCode, e.URL, e.Body)
+}
+
+func newHTTPError(url string, resp *http.Response) error {
+	if resp.Request != nil {
+		url = resp.Request.URL.String()
+	}
+	body, _ := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	return &HTTPError{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Body:       body,
+		URL:        url,
+	}
+}
+
+func success(code int) bool {
+	return code >= 200 && code < 300
+}
+
+// closeBody 读完剩余 body 后关闭, 使连接可以复用
+func closeBody(resp *http.Response) {
+	io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxDrainBody))
+	resp.Body.Close()
+}
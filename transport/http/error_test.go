@@ -0,0 +1,135 @@
+package http_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+// trackedBody 记录响应 body 读取的字节数及是否关闭
+type trackedBody struct {
+	io.ReadCloser
+	mutex  sync.Mutex
+	read   int
+	closed bool
+}
+
+func (b *trackedBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.mutex.Lock()
+	b.read += n
+	b.mutex.Unlock()
+	return n, err
+}
+
+func (b *trackedBody) Close() error {
+	b.mutex.Lock()
+	b.closed = true
+	b.mutex.Unlock()
+	return b.ReadCloser.Close()
+}
+
+func trackBody(last **trackedBody) xhttp.Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return xhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			resp, err := next.RoundTrip(req)
+			if err != nil {
+				return resp, err
+			}
+			body := &trackedBody{ReadCloser: resp.Body}
+			resp.Body = body
+			*last = body
+			return resp, nil
+		})
+	}
+}
+
+// codeServer 按 path 中的状态码返回, body 为 size 参数指定长度的 'x'
+func codeServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/"))
+		size, _ := strconv.Atoi(r.URL.Query().Get("size"))
+		w.Header().Set("X-Code", strconv.Itoa(code))
+		w.WriteHeader(code)
+		w.Write(bytes.Repeat([]byte("x"), size))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPError(t *testing.T) {
+	srv := codeServer(t)
+	var body *trackedBody
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(trackBody(&body)))
+
+	err := c.Get(context.Background(), srv.URL+"/502", nil, xhttp.WithQuerySet("size", "10000"))
+	var httpErr *xhttp.HTTPError
+	if !errors.As(err, &httpErr) {
+		t.Fatalf("expect HTTPError, got %v", err)
+	}
+	if httpErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expect status 502, got %v", httpErr.StatusCode)
+	}
+	if got := httpErr.Header.Get("X-Code"); got != "502" {
+		t.Errorf("expect header X-Code 502, got %q", got)
+	}
+	if want := srv.URL + "/502?size=10000"; httpErr.URL != want {
+		t.Errorf("expect url %v, got %v", want, httpErr.URL)
+	}
+	if len(httpErr.Body) != 4096 || strings.Trim(string(httpErr.Body), "x") != "" {
+		t.Errorf("expect body truncated to 4096 bytes, got %d", len(httpErr.Body))
+	}
+
+	// 截断后剩余的 body 仍被读完并关闭, 连接可以复用
+	if body.read != 10000 || !body.closed {
+		t.Errorf("expect body drained and closed, read %d closed %v", body.read, body.closed)
+	}
+}
+
+func TestSuccessCodes(t *testing.T) {
+	srv := codeServer(t)
+	c := xhttp.NewHttpClient()
+
+	for _, code := range []int{200, 201, 202, 203, 204, 206, 299} {
+		var v map[string]interface{}
+		if err := c.Get(context.Background(), srv.URL+"/"+strconv.Itoa(code), &v); err != nil {
+			t.Errorf("code %v: %v", code, err)
+		}
+	}
+	for _, code := range []int{300, 304, 400, 500} {
+		var httpErr *xhttp.HTTPError
+		if err := c.Get(context.Background(), srv.URL+"/"+strconv.Itoa(code), nil); !errors.As(err, &httpErr) || httpErr.StatusCode != code {
+			t.Errorf("code %v: expect HTTPError, got %v", code, err)
+		}
+	}
+}
+
+func TestResponseHandlerBody(t *testing.T) {
+	srv := codeServer(t)
+	var body *trackedBody
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(trackBody(&body)))
+
+	// 回调未读 body 时由 client 读完并关闭, 非 2xx 也交给回调处理
+	errHandled := errors.New("handled")
+	err := c.Get(context.Background(), srv.URL+"/500", nil, xhttp.WithQuerySet("size", "2000"), xhttp.WithResponse(func(resp *http.Response) error {
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Errorf("expect status 500, got %v", resp.StatusCode)
+		}
+		return errHandled
+	}))
+	if !errors.Is(err, errHandled) {
+		t.Fatalf("expect handler error, got %v", err)
+	}
+	if body.read != 2000 || !body.closed {
+		t.Errorf("expect body drained and closed, read %d closed %v", body.read, body.closed)
+	}
+}
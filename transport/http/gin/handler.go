@@ -1,6 +1,7 @@
 package gin
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"path"
@@ -9,6 +10,9 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type Handler struct {
@@ -84,7 +88,9 @@ func (h *Handler) ginHandler(methodSpec *ServiceMethod) gin.HandlerFunc {
 		md.Set("client-ip", ctx.ClientIP())
 		md = Join(md, Metadata(r.URL.Query()), ginParams(ctx.Params))
 
-		newCtx := NewContextFromMetadata(r.Context(), md)
+		newCtx := traceContext(r)
+		newCtx = NewContextFromMetadata(newCtx, md)
+		newCtx = NewContextFromHeader(newCtx, r.Header.Clone())
 		r = r.WithContext(newCtx)
 
 		reply, err := h.doHandle(r, methodSpec)
@@ -97,6 +103,16 @@ func (h *Handler) ginHandler(methodSpec *ServiceMethod) gin.HandlerFunc {
 	}
 }
 
+// traceContext 返回作为下游 db 等 span 父级的 ctx: 已有 span(如 otelgin 的 server span)时直接使用,
+// 否则以上游 trace context 为父
+func traceContext(r *http.Request) context.Context {
+	ctx := r.Context()
+	if trace.SpanContextFromContext(ctx).IsValid() {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, propagation.HeaderCarrier(r.Header))
+}
+
 func (s *Handler) doHandle(r *http.Request, methodSpec *ServiceMethod) (reply interface{}, err error) {
 	ctx := r.Context()
 
@@ -0,0 +1,52 @@
+package gin
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTraceContext(t *testing.T) {
+	prev := otel.GetTextMapPropagator()
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() { otel.SetTextMapPropagator(prev) })
+
+	const traceparent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	remote, _ := trace.SpanIDFromHex("00f067aa0ba902b7")
+	local := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{1},
+		SpanID:     trace.SpanID{2},
+		TraceFlags: trace.FlagsSampled,
+	})
+
+	tests := []struct {
+		name   string
+		header string
+		span   trace.SpanContext
+		want   trace.SpanID
+	}{
+		{name: "upstream traceparent", header: traceparent, want: remote},
+		{name: "server span kept", header: traceparent, span: local, want: local.SpanID()},
+		{name: "no trace", want: trace.SpanID{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/svc/Method", nil)
+			if tt.header != "" {
+				r.Header.Set("traceparent", tt.header)
+			}
+			if tt.span.IsValid() {
+				r = r.WithContext(trace.ContextWithSpanContext(context.Background(), tt.span))
+			}
+
+			got := trace.SpanContextFromContext(traceContext(r)).SpanID()
+			if got != tt.want {
+				t.Fatalf("expect parent span %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
@@ -1,18 +1,37 @@
 package gin
 
-import "context"
-import "strings"
+import (
+	"context"
+	"net/http"
+	"strings"
 
-type metadataKey struct{}
+	xhttp "github.com/sujunbo/micro/transport/http"
+	"github.com/sujunbo/micro/transport/incoming"
+)
+
+func init() {
+	xhttp.RegisterHeaderFunc(HeaderFromContext)
+}
 
 type Metadata map[string][]string
 
+// MetadataFromContext ctx 中没有 Metadata 时返回 nil, 可安全调用 Get
 func MetadataFromContext(ctx context.Context) Metadata {
-	return ctx.Value(metadataKey{}).(Metadata)
+	return incoming.Metadata(ctx)
 }
 
 func NewContextFromMetadata(ctx context.Context, md Metadata) context.Context {
-	return context.WithValue(ctx, metadataKey{}, md)
+	return incoming.NewContextWithMetadata(ctx, md)
+}
+
+// HeaderFromContext 返回入站请求的 header, 与 Metadata 分开保存, 不含 url 参数, 可作为可信的调用方身份来源;
+// ctx 中没有时返回 nil
+func HeaderFromContext(ctx context.Context) http.Header {
+	return incoming.Header(ctx)
+}
+
+func NewContextFromHeader(ctx context.Context, h http.Header) context.Context {
+	return incoming.NewContextWithHeader(ctx, h)
 }
 
 func (m Metadata) Set(key, val string) {
@@ -0,0 +1,28 @@
+package http
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var requestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+	Namespace: "http_client",
+	Name:      "request_duration_seconds",
+	Help:      "Outbound HTTP request latency by service, host, method and status code.",
+	Buckets:   []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+}, []string{"service", "host", "method", "code"})
+
+func init() {
+	prometheus.MustRegister(requestDuration)
+}
+
+func observeRequest(service string, req *http.Request, resp *http.Response, err error, cost time.Duration) {
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(resp.StatusCode)
+	}
+	requestDuration.WithLabelValues(service, req.URL.Host, req.Method, code).Observe(cost.Seconds())
+}
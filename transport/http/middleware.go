@@ -0,0 +1,132 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/sujunbo/micro/log"
+	"github.com/sujunbo/micro/util/uuid"
+)
+
+// Middleware 包装 http.RoundTripper, 先注册的在最外层, 重试时每次尝试都会经过
+type Middleware func(next http.RoundTripper) http.RoundTripper
+
+type RoundTripperFunc func(req *http.Request) (*http.Response, error)
+
+func (f RoundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func chainMiddlewares(rt http.RoundTripper, middlewares ...Middleware) http.RoundTripper {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		rt = middlewares[i](rt)
+	}
+	return rt
+}
+
+// LoggingMiddleware 记录出错, 5xx 及耗时超过 slow 的请求, 其余为 debug
+func LoggingMiddleware(slow time.Duration) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			cost := time.Since(start)
+
+			switch {
+			case err != nil:
+				log.Warnf("http client method:%v url:%v cost:%v err:%v", req.Method, req.URL, cost, err)
+			case resp.StatusCode >= http.StatusInternalServerError || (slow > 0 && cost >= slow):
+				log.Warnf("http client method:%v url:%v code:%v cost:%v", req.Method, req.URL, resp.StatusCode, cost)
+			default:
+				log.Debugf("http client method:%v url:%v code:%v cost:%v", req.Method, req.URL, resp.StatusCode, cost)
+			}
+			return resp, err
+		})
+	}
+}
+
+// MetricsMiddleware 按 service/host/method/code 统计请求耗时
+func MetricsMiddleware(service string) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			start := time.Now()
+			resp, err := next.RoundTrip(req)
+			observeRequest(service, req, resp, err, time.Since(start))
+			return resp, err
+		})
+	}
+}
+
+// HeaderMiddleware 将 fn 返回的 header 加入请求, 不覆盖调用方已设置的值
+func HeaderMiddleware(fn func(ctx context.Context) http.Header) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			header := fn(req.Context())
+			if len(header) == 0 {
+				return next.RoundTrip(req)
+			}
+
+			req = req.Clone(req.Context())
+			for k, vs := range header {
+				if req.Header.Get(k) == "" {
+					req.Header[http.CanonicalHeaderKey(k)] = vs
+				}
+			}
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+// AuthMiddleware 注入 Authorization: Bearer <token>, token 每次请求获取以便刷新
+func AuthMiddleware(token func(ctx context.Context) (string, error)) Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get("Authorization") != "" {
+				return next.RoundTrip(req)
+			}
+
+			t, err := token(req.Context())
+			if err != nil {
+				return nil, err
+			}
+
+			req = req.Clone(req.Context())
+			req.Header.Set("Authorization", "Bearer "+t)
+			return next.RoundTrip(req)
+		})
+	}
+}
+
+const RequestIDHeader = "X-Request-Id"
+
+type requestIDKey struct{}
+
+func NewContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// RequestIDMiddleware 设置 X-Request-Id, 优先使用 ctx 中的 id, 否则生成新的
+func RequestIDMiddleware() Middleware {
+	return func(next http.RoundTripper) http.RoundTripper {
+		return RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+			if req.Header.Get(RequestIDHeader) != "" {
+				return next.RoundTrip(req)
+			}
+
+			id := RequestIDFromContext(req.Context())
+			if id == "" {
+				id = uuid.New()
+			}
+
+			req = req.Clone(req.Context())
+			req.Header.Set(RequestIDHeader, id)
+			return next.RoundTrip(req)
+		})
+	}
+}
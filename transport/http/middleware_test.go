@@ -0,0 +1,229 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sujunbo/micro/log"
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+// headerServer 返回收到的请求头, path 为 /500 时返回 500, /slow 时延迟返回
+func headerServer(t *testing.T) (*httptest.Server, func() http.Header) {
+	var mutex sync.Mutex
+	var last http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mutex.Lock()
+		last = r.Header.Clone()
+		mutex.Unlock()
+
+		switch r.URL.Path {
+		case "/500":
+			w.WriteHeader(http.StatusInternalServerError)
+		case "/slow":
+			time.Sleep(50 * time.Millisecond)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv, func() http.Header {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return last
+	}
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	srv, _ := headerServer(t)
+
+	var trace []string
+	mw := func(name string) xhttp.Middleware {
+		return func(next http.RoundTripper) http.RoundTripper {
+			return xhttp.RoundTripperFunc(func(req *http.Request) (*http.Response, error) {
+				trace = append(trace, name+">")
+				resp, err := next.RoundTrip(req)
+				trace = append(trace, "<"+name)
+				return resp, err
+			})
+		}
+	}
+
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(mw("a"), mw("b")), xhttp.WithMiddleware(mw("c")))
+	if err := c.Get(context.Background(), srv.URL, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	want := "a> b> c> <c <b <a"
+	if got := strings.Join(trace, " "); got != want {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	srv, last := headerServer(t)
+	tokens := 0
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(xhttp.AuthMiddleware(func(ctx context.Context) (string, error) {
+		tokens++
+		return fmt.Sprintf("token-%d", tokens), nil
+	})))
+	ctx := context.Background()
+
+	// 每次请求重新获取 token
+	for _, want := range []string{"Bearer token-1", "Bearer token-2"} {
+		if err := c.Get(ctx, srv.URL, nil); err != nil {
+			t.Fatal(err)
+		}
+		if got := last().Get("Authorization"); got != want {
+			t.Fatalf("expect %v, got %v", want, got)
+		}
+	}
+
+	// 调用方已设置时不覆盖
+	if err := c.Get(ctx, srv.URL, nil, xhttp.WithHeaderSet("Authorization", "Basic abc")); err != nil {
+		t.Fatal(err)
+	}
+	if got := last().Get("Authorization"); got != "Basic abc" || tokens != 2 {
+		t.Fatalf("expect caller Authorization kept, got %v (tokens %d)", got, tokens)
+	}
+
+	// 获取 token 失败时不发送请求
+	errToken := errors.New("token expired")
+	failing := xhttp.NewHttpClient(xhttp.WithMiddleware(xhttp.AuthMiddleware(func(ctx context.Context) (string, error) {
+		return "", errToken
+	})))
+	if err := failing.Get(ctx, srv.URL+"/never", nil); !errors.Is(err, errToken) {
+		t.Fatalf("expect token err, got %v", err)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	srv, last := headerServer(t)
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(xhttp.RequestIDMiddleware()))
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+		opts []xhttp.RequestOption
+		want string
+	}{
+		{name: "from ctx", ctx: xhttp.NewContextWithRequestID(context.Background(), "req-1"), want: "req-1"},
+		{name: "caller header", ctx: xhttp.NewContextWithRequestID(context.Background(), "req-1"), opts: []xhttp.RequestOption{xhttp.WithHeaderSet(xhttp.RequestIDHeader, "req-2")}, want: "req-2"},
+		{name: "generated", ctx: context.Background()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := c.Get(tt.ctx, srv.URL, nil, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			got := last().Get(xhttp.RequestIDHeader)
+			if tt.want != "" && got != tt.want || got == "" {
+				t.Fatalf("expect request id %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+// recordLogger 按级别记录日志
+type recordLogger struct {
+	mutex sync.Mutex
+	lines []string
+}
+
+func (l *recordLogger) add(level, format string, a ...interface{}) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	l.lines = append(l.lines, level+" "+fmt.Sprintf(format, a...))
+}
+
+func (l *recordLogger) last() string {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	if len(l.lines) == 0 {
+		return ""
+	}
+	return l.lines[len(l.lines)-1]
+}
+
+func (l *recordLogger) Debug(msg string)                       { l.add("debug", "%s", msg) }
+func (l *recordLogger) Debugf(format string, a ...interface{}) { l.add("debug", format, a...) }
+func (l *recordLogger) Info(msg string)                        { l.add("info", "%s", msg) }
+func (l *recordLogger) Infof(format string, a ...interface{})  { l.add("info", format, a...) }
+func (l *recordLogger) Warn(msg string)                        { l.add("warn", "%s", msg) }
+func (l *recordLogger) Warnf(format string, a ...interface{})  { l.add("warn", format, a...) }
+func (l *recordLogger) Error(msg string)                       { l.add("error", "%s", msg) }
+func (l *recordLogger) Errorf(format string, a ...interface{}) { l.add("error", format, a...) }
+
+func TestLoggingMiddleware(t *testing.T) {
+	logger := &recordLogger{}
+	log.SetLogger(logger)
+	t.Cleanup(func() { log.SetLogger(nil) })
+
+	srv, _ := headerServer(t)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(xhttp.LoggingMiddleware(30 * time.Millisecond)))
+	tests := []struct {
+		url   string
+		level string
+		want  string
+	}{
+		{srv.URL + "/ok", "debug ", "code:200"},
+		{srv.URL + "/500", "warn ", "code:500"},
+		{srv.URL + "/slow", "warn ", "code:200"},
+		{closed.URL, "warn ", "err:"},
+	}
+	for _, tt := range tests {
+		c.Get(context.Background(), tt.url, nil)
+		got := logger.last()
+		if !strings.HasPrefix(got, tt.level) || !strings.Contains(got, tt.want) {
+			t.Errorf("%v: expect %v log with %q, got %q", tt.url, strings.TrimSpace(tt.level), tt.want, got)
+		}
+	}
+}
+
+func TestMetricsMiddleware(t *testing.T) {
+	srv, _ := headerServer(t)
+	closed := httptest.NewServer(http.NotFoundHandler())
+	closed.Close()
+
+	c := xhttp.NewHttpClient(xhttp.WithMiddleware(xhttp.MetricsMiddleware(t.Name())))
+	c.Get(context.Background(), srv.URL, nil)
+	c.Get(context.Background(), srv.URL+"/500", nil)
+	c.Get(context.Background(), srv.URL+"/500", nil)
+	c.Get(context.Background(), closed.URL, nil)
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	counts := map[string]uint64{}
+	for _, f := range families {
+		if f.GetName() != "http_client_request_duration_seconds" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			labels := map[string]string{}
+			for _, l := range m.GetLabel() {
+				labels[l.GetName()] = l.GetValue()
+			}
+			if labels["service"] == t.Name() && labels["method"] == http.MethodGet {
+				counts[labels["code"]] += m.GetHistogram().GetSampleCount()
+			}
+		}
+	}
+
+	want := map[string]uint64{"200": 1, "500": 2, "error": 1}
+	for code, n := range want {
+		if counts[code] != n {
+			t.Errorf("code %v: expect %d samples, got %d", code, n, counts[code])
+		}
+	}
+}
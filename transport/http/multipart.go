@@ -0,0 +1,67 @@
+package http
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+)
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+// Multipart 作为请求 body 时以 multipart/form-data 上传, 内容先缓存在内存中
+type Multipart struct {
+	Fields map[string]string
+	Files  []File
+}
+
+type File struct {
+	// 表单字段名
+	Field string
+	// 文件名
+	Name        string
+	ContentType string
+	Reader      io.Reader
+}
+
+func (m *Multipart) encode() (reader io.Reader, contentType string, err error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	for k, v := range m.Fields {
+		if err = w.WriteField(k, v); err != nil {
+			return
+		}
+	}
+
+	for _, f := range m.Files {
+		if f.Reader == nil {
+			return nil, "", fmt.Errorf("multipart file field:%v name:%v reader is nil", f.Field, f.Name)
+		}
+
+		var part io.Writer
+		if f.ContentType == "" {
+			part, err = w.CreateFormFile(f.Field, f.Name)
+		} else {
+			h := textproto.MIMEHeader{}
+			h.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+				quoteEscaper.Replace(f.Field), quoteEscaper.Replace(f.Name)))
+			h.Set("Content-Type", f.ContentType)
+			part, err = w.CreatePart(h)
+		}
+		if err != nil {
+			return
+		}
+
+		if _, err = io.Copy(part, f.Reader); err != nil {
+			return
+		}
+	}
+
+	if err = w.Close(); err != nil {
+		return
+	}
+	return &buf, w.FormDataContentType(), nil
+}
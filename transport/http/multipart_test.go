@@ -0,0 +1,80 @@
+package http_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+func TestMultipart(t *testing.T) {
+	type part struct {
+		name, filename, contentType, body string
+	}
+	var parts []part
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mr, err := r.MultipartReader()
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		for {
+			p, err := mr.NextPart()
+			if err != nil {
+				break
+			}
+			b, _ := io.ReadAll(p)
+			parts = append(parts, part{p.FormName(), p.FileName(), p.Header.Get("Content-Type"), string(b)})
+		}
+	}))
+	defer srv.Close()
+
+	c := xhttp.NewHttpClient()
+	err := c.Post(context.Background(), srv.URL, &xhttp.Multipart{
+		Fields: map[string]string{"shop_id": "439510"},
+		Files: []xhttp.File{
+			{Field: "file", Name: `a"b.csv`, ContentType: "text/csv", Reader: strings.NewReader("id\n1\n")},
+			{Field: "raw", Name: "raw.bin", Reader: strings.NewReader("raw")},
+		},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []part{
+		{"shop_id", "", "", "439510"},
+		{"file", `a"b.csv`, "text/csv", "id\n1\n"},
+		{"raw", "raw.bin", "application/octet-stream", "raw"},
+	}
+	if len(parts) != len(want) {
+		t.Fatalf("expect %d parts, got %+v", len(want), parts)
+	}
+	for i := range want {
+		if parts[i] != want[i] {
+			t.Errorf("part %d: expect %+v, got %+v", i, want[i], parts[i])
+		}
+	}
+}
+
+func TestMultipartNilReader(t *testing.T) {
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits++
+	}))
+	defer srv.Close()
+
+	c := xhttp.NewHttpClient()
+	err := c.Post(context.Background(), srv.URL, &xhttp.Multipart{
+		Files: []xhttp.File{{Field: "file", Name: "a.csv"}},
+	}, nil)
+	if err == nil || !strings.Contains(err.Error(), "reader is nil") {
+		t.Fatalf("expect nil reader error, got %v", err)
+	}
+	if hits != 0 {
+		t.Fatalf("expect no request sent, got %d", hits)
+	}
+}
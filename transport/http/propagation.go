@@ -0,0 +1,42 @@
+package http
+
+import (
+	"context"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// DefaultPropagationKeys 默认透传到下游的入站 header; x-user-id 等身份信息需通过 WithPropagation 显式开启
+var DefaultPropagationKeys = []string{"x-request-id"}
+
+// HeaderFunc 从 ctx 读取入站请求的 header
+type HeaderFunc func(ctx context.Context) http.Header
+
+var headerFunc HeaderFunc
+
+// RegisterHeaderFunc 由 transport/http/gin 在 init 中注册, 避免 http 包反向依赖 gin
+func RegisterHeaderFunc(fn HeaderFunc) {
+	headerFunc = fn
+}
+
+// PropagationMiddleware 将入站 header 中 keys 对应的值写入出站 header, 并注入当前 trace context;
+// 只读取入站 header, 不读取 url 参数等其他元数据
+func PropagationMiddleware(keys ...string) Middleware {
+	return HeaderMiddleware(func(ctx context.Context) http.Header {
+		header := http.Header{}
+		if headerFunc != nil {
+			if in := headerFunc(ctx); in != nil {
+				for _, k := range keys {
+					if vs := in.Values(k); len(vs) > 0 {
+						header[http.CanonicalHeaderKey(k)] = vs
+					}
+				}
+			}
+		}
+
+		otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+		return header
+	})
+}
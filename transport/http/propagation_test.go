@@ -0,0 +1,55 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+	"github.com/sujunbo/micro/transport/http/gin"
+)
+
+func TestPropagation(t *testing.T) {
+	var got http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header
+	}))
+	defer srv.Close()
+
+	// url 参数 ?x-user-id=admin 进入 Metadata, 不应被透传
+	ctx := gin.NewContextFromMetadata(context.Background(), gin.Metadata{"x-user-id": {"admin"}, "x-request-id": {"q"}})
+	ctx = gin.NewContextFromHeader(ctx, http.Header{
+		"X-Request-Id":  {"r1"},
+		"X-User-Id":     {"alice"},
+		"Authorization": {"Bearer secret"},
+	})
+
+	tests := []struct {
+		name    string
+		opts    []xhttp.Option
+		request string
+		user    string
+	}{
+		{name: "default", request: "r1"},
+		{name: "user opt-in", opts: []xhttp.Option{xhttp.WithPropagation("x-request-id", "x-user-id")}, request: "r1", user: "alice"},
+		{name: "disabled", opts: []xhttp.Option{xhttp.WithPropagation()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := xhttp.NewHttpClient(tt.opts...).Get(ctx, srv.URL, nil); err != nil {
+				t.Fatal(err)
+			}
+
+			if v := got.Get("X-Request-Id"); v != tt.request {
+				t.Errorf("X-Request-Id = %q, want %q", v, tt.request)
+			}
+			if v := got.Get("X-User-Id"); v != tt.user {
+				t.Errorf("X-User-Id = %q, want %q", v, tt.user)
+			}
+			if v := got.Get("Authorization"); v != "" {
+				t.Errorf("Authorization must not be propagated, got %q", v)
+			}
+		})
+	}
+}
@@ -0,0 +1,175 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/sujunbo/micro/log"
+)
+
+// RetryPolicy 重试策略, 通过 WithRetry 开启, 零值字段使用默认值
+type RetryPolicy struct {
+	// 总尝试次数(含首次), 默认 3
+	MaxAttempts int
+	// 首次重试等待, 之后按指数增长, 默认 100ms
+	Backoff time.Duration
+	// 单次等待上限, 默认 2s
+	MaxBackoff time.Duration
+	// 随机抖动比例 (0, 1], 实际等待为 [wait*(1-Jitter), wait], 默认 0.5
+	Jitter float64
+	// 可重试的状态码, 默认 429/502/503/504; Retry-After 超过 MaxBackoff 时不再重试, 直接返回该响应
+	RetryStatus []int
+	// 可重试的错误, 默认连接被重置/拒绝/提前关闭及网络超时
+	RetryError func(error) bool
+}
+
+func (p *RetryPolicy) apply() {
+	if p.MaxAttempts == 0 {
+		p.MaxAttempts = 3
+	}
+
+	if p.Backoff == 0 {
+		p.Backoff = 100 * time.Millisecond
+	}
+
+	if p.MaxBackoff == 0 {
+		p.MaxBackoff = 2 * time.Second
+	}
+
+	if p.Jitter == 0 {
+		p.Jitter = 0.5
+	}
+
+	if p.RetryStatus == nil {
+		p.RetryStatus = []int{
+			http.StatusTooManyRequests,
+			http.StatusBadGateway,
+			http.StatusServiceUnavailable,
+			http.StatusGatewayTimeout,
+		}
+	}
+
+	if p.RetryError == nil {
+		p.RetryError = isRetryableError
+	}
+}
+
+func isRetryableError(err error) bool {
+	if errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var ne net.Error
+	return errors.As(err, &ne) && ne.Timeout()
+}
+
+// idempotent 默认只重试幂等方法, 可用 WithRetryable 覆盖
+func idempotent(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace, http.MethodPut, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+func (p *RetryPolicy) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return p.RetryError(err)
+	}
+
+	for _, code := range p.RetryStatus {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
+
+// backoff 返回第 attempt 次失败后的等待时间; Retry-After 超过 MaxBackoff 时 ok 为 false, 不再重试
+func (p *RetryPolicy) backoff(attempt int, resp *http.Response) (wait time.Duration, ok bool) {
+	wait = p.Backoff << uint(attempt-1)
+	if wait <= 0 || wait > p.MaxBackoff {
+		wait = p.MaxBackoff
+	}
+	wait -= time.Duration(rand.Float64() * p.Jitter * float64(wait))
+
+	if resp != nil {
+		after := retryAfter(resp.Header.Get("Retry-After"))
+		if after > p.MaxBackoff {
+			return 0, false
+		}
+		if after > wait {
+			wait = after
+		}
+	}
+	return wait, true
+}
+
+// retryAfter 解析秒数或 HTTP-date 格式的 Retry-After
+func retryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// do 缓存请求体以便每次重试重新发送
+func (p *RetryPolicy) do(ctx context.Context, url string, reader io.Reader, send func(io.Reader) (*http.Response, error)) (resp *http.Response, err error) {
+	var body []byte
+	if reader != nil {
+		if body, err = ioutil.ReadAll(reader); err != nil {
+			return
+		}
+	}
+
+	for attempt := 1; ; attempt++ {
+		var r io.Reader
+		if body != nil {
+			r = bytes.NewReader(body)
+		}
+
+		resp, err = send(r)
+		if ctx.Err() != nil || attempt >= p.MaxAttempts || !p.retryable(resp, err) {
+			return
+		}
+
+		// 等待超过剩余时间时直接返回本次结果, 而不是等到 ctx 超时
+		wait, ok := p.backoff(attempt, resp)
+		if deadline, has := ctx.Deadline(); !ok || has && time.Until(deadline) <= wait {
+			return
+		}
+
+		if resp != nil {
+			log.Warnf("http retry url:%v attempt:%v code:%v wait:%v", url, attempt, resp.StatusCode, wait)
+			closeBody(resp)
+		} else {
+			log.Warnf("http retry url:%v attempt:%v err:%v wait:%v", url, attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package http_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	xhttp "github.com/sujunbo/micro/transport/http"
+)
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name       string
+		retryAfter string
+		timeout    time.Duration
+		hits       int
+	}{
+		{name: "short retry-after", retryAfter: "0", hits: 3},
+		{name: "retry-after over max backoff", retryAfter: "3600", hits: 1},
+		{name: "backoff over deadline", timeout: 100 * time.Millisecond, hits: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hits := 0
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				hits++
+				if tt.retryAfter != "" {
+					w.Header().Set("Retry-After", tt.retryAfter)
+				}
+				w.WriteHeader(http.StatusServiceUnavailable)
+			}))
+			defer srv.Close()
+
+			c := xhttp.NewHttpClient(xhttp.WithRetry(xhttp.RetryPolicy{Backoff: time.Second, MaxBackoff: 2 * time.Second}))
+			opts := []xhttp.RequestOption{}
+			if tt.timeout > 0 {
+				opts = append(opts, xhttp.WithRequestTimeout(tt.timeout))
+			}
+
+			start := time.Now()
+			err := c.Get(context.Background(), srv.URL, nil, opts...)
+
+			var he *xhttp.HTTPError
+			if !errors.As(err, &he) || he.StatusCode != http.StatusServiceUnavailable {
+				t.Fatalf("expect 503 HTTPError, got %v", err)
+			}
+			if hits != tt.hits {
+				t.Fatalf("expect %d attempts, got %d", tt.hits, hits)
+			}
+			if tt.hits == 1 && time.Since(start) > 500*time.Millisecond {
+				t.Fatalf("expect no wait, took %v", time.Since(start))
+			}
+		})
+	}
+}
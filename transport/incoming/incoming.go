@@ -0,0 +1,34 @@
+// Package incoming 在 ctx 中保存入站请求的 header 与元数据, 只依赖标准库,
+// 由 transport/http/gin 写入, db 等包读取而无需依赖 gin
+package incoming
+
+import (
+	"context"
+	"net/http"
+)
+
+type metadataKey struct{}
+
+type headerKey struct{}
+
+// NewContextWithMetadata 保存入站请求的元数据, key 为小写
+func NewContextWithMetadata(ctx context.Context, md map[string][]string) context.Context {
+	return context.WithValue(ctx, metadataKey{}, md)
+}
+
+// Metadata ctx 中没有时返回 nil
+func Metadata(ctx context.Context) map[string][]string {
+	md, _ := ctx.Value(metadataKey{}).(map[string][]string)
+	return md
+}
+
+// NewContextWithHeader 保存入站请求的 header, 与元数据分开, 不含 url 参数
+func NewContextWithHeader(ctx context.Context, h http.Header) context.Context {
+	return context.WithValue(ctx, headerKey{}, h)
+}
+
+// Header ctx 中没有时返回 nil, 可安全调用 Get
+func Header(ctx context.Context) http.Header {
+	h, _ := ctx.Value(headerKey{}).(http.Header)
+	return h
+}
@@ -0,0 +1,236 @@
+// Package breaker 按错误率与耗时分位熔断, 状态为 closed -> open -> half-open -> closed
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/sujunbo/micro/log"
+)
+
+var (
+	ErrOpen = errors.New("circuit breaker is open")
+)
+
+type State int
+
+const (
+	StateClosed State = iota
+	StateOpen
+	StateHalfOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateOpen:
+		return "open"
+	case StateHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+var (
+	stateGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "circuit_breaker_state",
+		Help: "Circuit breaker state: 0 closed, 1 open, 2 half-open.",
+	}, []string{"name"})
+
+	rejected = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "circuit_breaker_rejected_total",
+		Help: "Requests rejected by an open circuit breaker.",
+	}, []string{"name"})
+)
+
+func init() {
+	prometheus.MustRegister(stateGauge, rejected)
+}
+
+const bucketNum = 10
+
+type bucket struct {
+	start    time.Time
+	total    uint64
+	failures uint64
+	slow     uint64
+}
+
+type Breaker struct {
+	opts options
+
+	mutex    sync.Mutex
+	state    State
+	openedAt time.Time
+	buckets  [bucketNum]bucket
+	// 半开状态下已放行与已成功的探测数
+	probes    uint64
+	successes uint64
+	// 每次状态变化加一, 丢弃之前状态下放行的请求结果
+	generation uint64
+}
+
+func New(opts ...Option) *Breaker {
+	opt := options{}
+	for _, o := range opts {
+		o(&opt)
+	}
+	opt.apply()
+
+	stateGauge.WithLabelValues(opt.name).Set(float64(StateClosed))
+	return &Breaker{opts: opt}
+}
+
+func (b *Breaker) Name() string {
+	return b.opts.name
+}
+
+func (b *Breaker) State() State {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.expire(b.opts.now())
+	return b.state
+}
+
+// Allow 检查是否放行, 放行时调用方需在请求结束后调用 done 上报结果
+func (b *Breaker) Allow() (done func(failed bool, cost time.Duration), err error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.expire(b.opts.now())
+
+	switch b.state {
+	case StateOpen:
+		rejected.WithLabelValues(b.opts.name).Inc()
+		return nil, ErrOpen
+	case StateHalfOpen:
+		if b.probes >= b.opts.halfOpenRequests {
+			rejected.WithLabelValues(b.opts.name).Inc()
+			return nil, ErrOpen
+		}
+		b.probes++
+	}
+
+	state, generation := b.state, b.generation
+	return func(failed bool, cost time.Duration) {
+		b.done(state, generation, failed, cost)
+	}, nil
+}
+
+// Do 在熔断保护下执行 fn, fn 返回的错误均计为失败
+func (b *Breaker) Do(fn func() error) error {
+	done, err := b.Allow()
+	if err != nil {
+		return err
+	}
+
+	start := b.opts.now()
+	err = fn()
+	done(err != nil, b.opts.now().Sub(start))
+	return err
+}
+
+func (b *Breaker) done(state State, generation uint64, failed bool, cost time.Duration) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	// 结果对应的是已经结束的状态周期
+	if generation != b.generation {
+		return
+	}
+
+	now := b.opts.now()
+	if state == StateHalfOpen {
+		if failed {
+			b.setState(StateOpen, now)
+			return
+		}
+
+		b.successes++
+		if b.successes >= b.opts.halfOpenRequests {
+			b.setState(StateClosed, now)
+		}
+		return
+	}
+
+	bk := b.bucket(now)
+	bk.total++
+	if failed {
+		bk.failures++
+	}
+	if b.opts.latency > 0 && cost > b.opts.latency {
+		bk.slow++
+	}
+
+	if b.shouldTrip(now) {
+		b.setState(StateOpen, now)
+	}
+}
+
+func (b *Breaker) bucket(now time.Time) *bucket {
+	width := b.opts.window / bucketNum
+	start := now.Truncate(width)
+	bk := &b.buckets[int(start.UnixNano()/int64(width))%bucketNum]
+	if !bk.start.Equal(start) {
+		*bk = bucket{start: start}
+	}
+	return bk
+}
+
+func (b *Breaker) shouldTrip(now time.Time) bool {
+	var total, failures, slow uint64
+	for _, bk := range b.buckets {
+		if now.Sub(bk.start) >= b.opts.window {
+			continue
+		}
+		total += bk.total
+		failures += bk.failures
+		slow += bk.slow
+	}
+
+	if total < b.opts.minRequests {
+		return false
+	}
+
+	if float64(failures)/float64(total) >= b.opts.errorRate {
+		return true
+	}
+
+	// 未超过 latency 的请求占比低于 percentile, 即分位耗时超过 latency;
+	// 不用 1-percentile 比较, 避免 1-0.9 的浮点误差使恰好 10% 的慢请求触发熔断
+	return b.opts.latency > 0 && float64(total-slow)/float64(total) < b.opts.percentile
+}
+
+// expire open 状态超时后进入 half-open
+func (b *Breaker) expire(now time.Time) {
+	if b.state == StateOpen && now.Sub(b.openedAt) >= b.opts.openTimeout {
+		b.setState(StateHalfOpen, now)
+	}
+}
+
+func (b *Breaker) setState(state State, now time.Time) {
+	from := b.state
+	if from == state {
+		return
+	}
+
+	b.state = state
+	b.generation++
+	b.probes, b.successes = 0, 0
+	switch state {
+	case StateOpen:
+		b.openedAt = now
+	case StateClosed:
+		b.buckets = [bucketNum]bucket{}
+	}
+
+	stateGauge.WithLabelValues(b.opts.name).Set(float64(state))
+	log.Warnf("circuit breaker name:%v state:%v -> %v", b.opts.name, from, state)
+	if b.opts.onStateChange != nil {
+		go b.opts.onStateChange(b.opts.name, from, state)
+	}
+}
@@ -0,0 +1,203 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+type clock struct {
+	t time.Time
+}
+
+func (c *clock) now() time.Time {
+	return c.t
+}
+
+func (c *clock) advance(d time.Duration) {
+	c.t = c.t.Add(d)
+}
+
+func newBreaker(t *testing.T, opts ...Option) (*Breaker, *clock) {
+	c := &clock{t: time.Date(2020, 4, 1, 0, 0, 0, 0, time.UTC)}
+	return New(append([]Option{WithName(t.Name()), WithClock(c.now)}, opts...)...), c
+}
+
+func record(t *testing.T, b *Breaker, failed bool, cost time.Duration) {
+	t.Helper()
+	done, err := b.Allow()
+	if err != nil {
+		t.Fatalf("expect allowed, got %v", err)
+	}
+	done(failed, cost)
+}
+
+func expectState(t *testing.T, b *Breaker, want State) {
+	t.Helper()
+	if got := b.State(); got != want {
+		t.Fatalf("expect %v, got %v", want, got)
+	}
+}
+
+func TestWindowRollover(t *testing.T) {
+	b, c := newBreaker(t, WithWindow(10*time.Second), WithMinRequests(4))
+
+	for i := 0; i < 3; i++ {
+		record(t, b, true, 0)
+	}
+
+	// 整个窗口之后落入同一个桶, 旧计数需要清零
+	c.advance(10 * time.Second)
+	record(t, b, true, 0)
+	expectState(t, b, StateClosed)
+
+	// 窗口内跨桶累计
+	c.advance(3 * time.Second)
+	record(t, b, false, 0)
+	c.advance(3 * time.Second)
+	record(t, b, true, 0)
+	expectState(t, b, StateClosed)
+	record(t, b, true, 0)
+	expectState(t, b, StateOpen)
+}
+
+func TestErrorRate(t *testing.T) {
+	b, _ := newBreaker(t, WithMinRequests(10), WithErrorRate(0.5))
+
+	for i := 0; i < 5; i++ {
+		record(t, b, false, 0)
+	}
+	for i := 0; i < 4; i++ {
+		record(t, b, true, 0)
+	}
+	// 未达到最小请求数
+	expectState(t, b, StateClosed)
+
+	record(t, b, false, 0) // 4/10
+	record(t, b, true, 0)  // 5/11
+	expectState(t, b, StateClosed)
+
+	record(t, b, true, 0) // 6/12
+	expectState(t, b, StateOpen)
+}
+
+func TestLatencyTrip(t *testing.T) {
+	b, _ := newBreaker(t, WithMinRequests(10), WithLatency(100*time.Millisecond, 0.9))
+
+	for i := 0; i < 9; i++ {
+		record(t, b, false, 10*time.Millisecond)
+	}
+	record(t, b, false, time.Second) // 1/10 未超过 p90
+	expectState(t, b, StateClosed)
+
+	record(t, b, false, time.Second) // 2/11
+	expectState(t, b, StateOpen)
+}
+
+func trip(t *testing.T, b *Breaker) {
+	t.Helper()
+	for i := 0; i < 20; i++ {
+		record(t, b, true, 0)
+	}
+	expectState(t, b, StateOpen)
+}
+
+func TestHalfOpen(t *testing.T) {
+	b, c := newBreaker(t, WithOpenTimeout(5*time.Second), WithHalfOpenRequests(2))
+	trip(t, b)
+
+	if _, err := b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expect ErrOpen, got %v", err)
+	}
+
+	c.advance(5*time.Second - time.Nanosecond)
+	expectState(t, b, StateOpen)
+	c.advance(time.Nanosecond)
+	expectState(t, b, StateHalfOpen)
+
+	p1, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	p2, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 超过探测数
+	if _, err = b.Allow(); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expect ErrOpen beyond probe limit, got %v", err)
+	}
+
+	p1(false, 0)
+	expectState(t, b, StateHalfOpen)
+	p2(false, 0)
+	expectState(t, b, StateClosed)
+}
+
+func TestProbeFailureReopens(t *testing.T) {
+	b, c := newBreaker(t, WithOpenTimeout(5*time.Second))
+	trip(t, b)
+
+	c.advance(5 * time.Second)
+	record(t, b, true, 0)
+	expectState(t, b, StateOpen)
+
+	// 重新计时
+	c.advance(5*time.Second - time.Nanosecond)
+	expectState(t, b, StateOpen)
+	c.advance(time.Nanosecond)
+	expectState(t, b, StateHalfOpen)
+}
+
+func TestLateDone(t *testing.T) {
+	b, c := newBreaker(t, WithOpenTimeout(5*time.Second), WithHalfOpenRequests(2))
+
+	// closed 状态放行的请求在熔断之后才返回
+	late, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	trip(t, b)
+	c.advance(5 * time.Second)
+	expectState(t, b, StateHalfOpen)
+
+	late(false, 0)
+	p1, _ := b.Allow()
+	p2, _ := b.Allow()
+	p1(false, 0)
+	expectState(t, b, StateHalfOpen)
+
+	// 上一个半开周期的探测在新的半开周期返回
+	p2(true, 0)
+	expectState(t, b, StateOpen)
+	c.advance(5 * time.Second)
+	expectState(t, b, StateHalfOpen)
+
+	stale, err := b.Allow()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale(true, 0)
+	expectState(t, b, StateOpen)
+	c.advance(5 * time.Second)
+	expectState(t, b, StateHalfOpen)
+
+	record(t, b, false, 0)
+	p1(false, 0)
+	p1(false, 0)
+	expectState(t, b, StateHalfOpen)
+	record(t, b, false, 0)
+	expectState(t, b, StateClosed)
+}
+
+func TestDo(t *testing.T) {
+	b, _ := newBreaker(t, WithMinRequests(1))
+	fail := errors.New("fail")
+
+	if err := b.Do(func() error { return fail }); err != fail {
+		t.Fatalf("expect fn err, got %v", err)
+	}
+	if err := b.Do(func() error { return nil }); !errors.Is(err, ErrOpen) {
+		t.Fatalf("expect ErrOpen, got %v", err)
+	}
+}
@@ -0,0 +1,111 @@
+package breaker
+
+import "time"
+
+type options struct {
+	name             string
+	window           time.Duration
+	minRequests      uint64
+	errorRate        float64
+	latency          time.Duration
+	percentile       float64
+	openTimeout      time.Duration
+	halfOpenRequests uint64
+	onStateChange    func(name string, from, to State)
+	now              func() time.Time
+}
+
+func (o *options) apply() {
+	if o.window == 0 {
+		o.window = 10 * time.Second
+	}
+
+	if o.minRequests == 0 {
+		o.minRequests = 20
+	}
+
+	if o.errorRate == 0 {
+		o.errorRate = 0.5
+	}
+
+	if o.percentile == 0 {
+		o.percentile = 0.99
+	}
+
+	if o.openTimeout == 0 {
+		o.openTimeout = 5 * time.Second
+	}
+
+	if o.halfOpenRequests == 0 {
+		o.halfOpenRequests = 1
+	}
+
+	if o.now == nil {
+		o.now = time.Now
+	}
+}
+
+type Option func(*options)
+
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithWindow 统计窗口, 默认 10s
+func WithWindow(window time.Duration) Option {
+	return func(o *options) {
+		o.window = window
+	}
+}
+
+// WithMinRequests 窗口内请求数达到该值才会熔断, 默认 20
+func WithMinRequests(n uint64) Option {
+	return func(o *options) {
+		o.minRequests = n
+	}
+}
+
+// WithErrorRate 错误率阈值, 默认 0.5
+func WithErrorRate(rate float64) Option {
+	return func(o *options) {
+		o.errorRate = rate
+	}
+}
+
+// WithLatency 窗口内 percentile 分位耗时超过 latency 时熔断, 默认不启用
+func WithLatency(latency time.Duration, percentile float64) Option {
+	return func(o *options) {
+		o.latency = latency
+		o.percentile = percentile
+	}
+}
+
+// WithOpenTimeout 熔断后进入半开状态的等待时间, 默认 5s
+func WithOpenTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.openTimeout = timeout
+	}
+}
+
+// WithHalfOpenRequests 半开状态允许的探测请求数, 全部成功后恢复, 默认 1
+func WithHalfOpenRequests(n uint64) Option {
+	return func(o *options) {
+		o.halfOpenRequests = n
+	}
+}
+
+// WithOnStateChange 状态变化回调, 在日志与指标之外额外调用
+func WithOnStateChange(fn func(name string, from, to State)) Option {
+	return func(o *options) {
+		o.onStateChange = fn
+	}
+}
+
+// WithClock 替换时钟, 用于测试, 默认 time.Now
+func WithClock(now func() time.Time) Option {
+	return func(o *options) {
+		o.now = now
+	}
+}